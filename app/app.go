@@ -0,0 +1,217 @@
+// ./app/app.go
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/controllers"
+	"github.com/Ana-Gabs/actividadr-back/logs"
+	"github.com/Ana-Gabs/actividadr-back/mailer"
+	"github.com/Ana-Gabs/actividadr-back/metrics"
+	"github.com/Ana-Gabs/actividadr-back/middlewares"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/routes"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Cola de correos en segundo plano: workers, envíos en espera y tiempo máximo de cada
+// envío (guardar el token y mandar el correo)
+const (
+	mailQueueWorkers = 2
+	mailQueueSize    = 100
+	mailSendTimeout  = 30 * time.Second
+)
+
+// App contiene todas las dependencias de una instancia del servicio
+type App struct {
+	Config      *config.Config
+	Mongo       *mongo.Client
+	DB          *mongo.Database
+	Logger      *logs.Logger
+	Users       repositories.UserRepository
+	Mailer      mailer.Mailer
+	MailQueue   *mailer.Queue
+	Revocations *utils.RevocationStore
+	Actions     *utils.ActionLogger
+	Metrics     *metrics.Metrics
+	Handler     *controllers.Handler
+	Server      *fiber.App
+
+	// ready es true mientras el servidor escucha y no se está apagando
+	ready atomic.Bool
+	// Tareas en segundo plano (StartBackgroundJobs) y cómo detenerlas
+	jobs     sync.WaitGroup
+	stopJobs context.CancelFunc
+}
+
+// New conecta con MongoDB y construye la aplicación a partir de cfg
+func New(cfg *config.Config) (*App, error) {
+	m := metrics.New()
+	client, db, err := config.ConnectMongo(cfg.Mongo, options.Client().SetMonitor(m.CommandMonitor()))
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := NewWithDatabase(cfg, client, db, m)
+	if err != nil {
+		config.CloseMongo(client)
+		return nil, err
+	}
+	return a, nil
+}
+
+// NewWithDatabase construye la aplicación sobre una conexión ya abierta. La latencia de
+// MongoDB solo se mide si el cliente se creó con m.CommandMonitor().
+func NewWithDatabase(cfg *config.Config, client *mongo.Client, db *mongo.Database, m *metrics.Metrics) (*App, error) {
+	passwords, err := newPasswords(cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := logs.New(cfg.LogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		logger.Close()
+		return nil, fmt.Errorf("error al configurar el envío de correos: %v", err)
+	}
+
+	a := &App{
+		Config:      cfg,
+		Mongo:       client,
+		DB:          db,
+		Logger:      logger,
+		Users:       repositories.NewMongoUserRepository(db.Collection("users")),
+		Mailer:      mail,
+		MailQueue:   mailer.NewQueue(mailQueueWorkers, mailQueueSize, mailSendTimeout),
+		Revocations: utils.NewRevocationStore(db.Collection("revoked_tokens")),
+		Actions:     utils.NewActionLogger(db.Collection("logs"), logger.Logger, cfg.Server.Environment),
+		Metrics:     m,
+	}
+	tokens := controllers.TokenStores{
+		RefreshTokens:  repositories.NewMongoRefreshTokenRepository(db.Collection("refresh_tokens")),
+		MFAChallenges:  repositories.NewMongoMFAChallengeRepository(db.Collection("mfa_challenges")),
+		PasswordResets: repositories.NewMongoPasswordResetRepository(db.Collection("password_resets")),
+	}
+	a.Handler = controllers.NewHandler(cfg, db, a.Users, tokens, passwords, a.Mailer, a.MailQueue, a.Revocations, a.Actions, a.Metrics, controllers.HealthProbes{
+		Ready:     a.Ready,
+		LogSink:   logger.Check,
+		StartedAt: time.Now(),
+	})
+	a.Server = a.newServer()
+	return a, nil
+}
+
+func (a *App) newServer() *fiber.App {
+	server := fiber.New()
+	server.Hooks().OnListen(func(fiber.ListenData) error {
+		a.ready.Store(true)
+		log.Printf("Servidor escuchando en %s", a.Addr())
+		return nil
+	})
+
+	// Middlewares; las métricas van primero para medir la petición completa
+	server.Use(a.Metrics.Middleware())
+	// Las acciones registradas por los handlers se guardan con el estado final de la respuesta
+	server.Use(a.Actions.Middleware())
+	server.Use(logger.New()) // Reemplazo de logMiddleware
+	server.Use(cors.New())   // Habilitar CORS
+
+	// Configurar rutas
+	routes.SetupHealthRoutes(server, a.Handler)
+	auth := middlewares.AuthMiddleware(a.Config.Auth.JWTSecret, a.Revocations)
+	routes.SetupMetricsRoutes(server, a.Metrics, a.Config.Auth.MetricsToken)
+	routes.SetupUserRoutes(server, a.Handler, auth)
+	routes.SetupLogsRoutes(server, a.Handler, auth)
+	routes.SetupAdminRoutes(server, a.Handler, auth)
+	return server
+}
+
+// Collections lista las colecciones de la base de datos configurada
+func (a *App) Collections() ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return a.DB.ListCollectionNames(ctx, bson.M{})
+}
+
+// StartBackgroundJobs arranca las tareas periódicas; Close las detiene y espera a que terminen
+func (a *App) StartBackgroundJobs() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopJobs = cancel
+
+	a.jobs.Add(1)
+	go func() {
+		defer a.jobs.Done()
+		// Limpieza periódica de cuentas que nunca se verificaron
+		a.Handler.RunUnverifiedAccountCleanup(ctx, time.Hour)
+	}()
+}
+
+// Ready indica si la instancia acepta tráfico: escucha y no se está apagando
+func (a *App) Ready() bool {
+	return a.ready.Load()
+}
+
+// Run sirve peticiones hasta que ctx se cancele (p. ej. por SIGTERM) y entonces apaga
+// el servidor de forma ordenada: deja de estar listo, deja de aceptar conexiones y espera
+// a las peticiones en curso como máximo SHUTDOWN_TIMEOUT
+func (a *App) Run(ctx context.Context) error {
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- a.Server.Listen(a.Addr())
+	}()
+
+	select {
+	case err := <-listenErr:
+		a.ready.Store(false)
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Apagando el servidor (espera máxima %s)...", a.Config.Server.ShutdownTimeout)
+	a.ready.Store(false)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.Config.Server.ShutdownTimeout)
+	defer cancel()
+	if err := a.Server.ShutdownWithContext(shutdownCtx); err != nil {
+		return fmt.Errorf("no se terminaron todas las peticiones en curso: %v", err)
+	}
+	return <-listenErr
+}
+
+// Addr es la dirección host:puerto en la que escucha el servidor
+func (a *App) Addr() string {
+	return net.JoinHostPort(a.Config.Server.Host, strconv.Itoa(a.Config.Server.Port))
+}
+
+// Close detiene las tareas en segundo plano, espera a los correos en cola, vacía los
+// logs y cierra MongoDB. Se llama después de Run, cuando ya no quedan peticiones en curso.
+func (a *App) Close() {
+	if a.stopJobs != nil {
+		a.stopJobs()
+	}
+	a.jobs.Wait()
+	a.MailQueue.Close()
+
+	if err := a.Logger.Close(); err != nil {
+		log.Println("Error al cerrar los archivos de log:", err)
+	}
+	config.CloseMongo(a.Mongo)
+}
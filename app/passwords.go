@@ -0,0 +1,49 @@
+// ./app/passwords.go
+package app
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/password"
+)
+
+// newPasswords construye la política, los parámetros de hash y la lista de contraseñas
+// filtradas (opcional) de la instancia a partir de la configuración
+func newPasswords(cfg config.PasswordConfig) (password.Passwords, error) {
+	hash := password.HashParams{
+		Algorithm:        strings.ToLower(cfg.HashAlgorithm),
+		BcryptCost:       cfg.BcryptCost,
+		Argon2Time:       uint32(cfg.Argon2Time),
+		Argon2MemoryKiB:  uint32(cfg.Argon2MemoryKiB),
+		Argon2Threads:    uint8(cfg.Argon2Threads),
+		Argon2KeyLength:  password.DefaultArgon2KeyLength,
+		Argon2SaltLength: password.DefaultArgon2SaltLength,
+	}
+
+	var breached *password.BreachedList
+	if cfg.BreachedHashesFile != "" {
+		list, err := password.LoadBreachedList(cfg.BreachedHashesFile)
+		if err != nil {
+			return password.Passwords{}, fmt.Errorf("error al cargar la lista de contraseñas filtradas: %v", err)
+		}
+		log.Printf("Lista de contraseñas filtradas cargada (%d hashes)", list.Len())
+		breached = list
+	}
+
+	return password.Passwords{
+		Policy: password.Policy{
+			MinLength:     cfg.MinLength,
+			MaxLength:     cfg.MaxLength,
+			RequireUpper:  cfg.RequireUpper,
+			RequireLower:  cfg.RequireLower,
+			RequireDigit:  cfg.RequireDigit,
+			RequireSymbol: cfg.RequireSymbol,
+			MaxBytes:      hash.MaxPasswordBytes(),
+			Breached:      breached,
+		},
+		Hash: hash,
+	}, nil
+}
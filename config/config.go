@@ -0,0 +1,92 @@
+// ./config/config.go
+package config
+
+import (
+	"time"
+)
+
+// Config es la configuración tipada de la aplicación.
+//
+// Cada campo declara su clave en el archivo de configuración (config), su variable de
+// entorno (env) y su valor por defecto (default). Los campos con secret:"true" no se
+// muestran al imprimir la configuración; con secret:"url" solo se oculta la contraseña.
+type Config struct {
+	Server ServerConfig `config:"server"`
+	Mongo  MongoConfig  `config:"mongo"`
+	Auth   AuthConfig   `config:"auth"`
+	Mail   MailConfig   `config:"mail"`
+	// Política de contraseñas y algoritmo con el que se guardan
+	Password PasswordConfig `config:"password"`
+	// Directorio de los archivos de log (error.log, combined.log, all.log)
+	LogDir string `config:"logDir" env:"LOG_DIR" default:"log"`
+}
+
+// ServerConfig es la dirección de escucha del servidor HTTP
+type ServerConfig struct {
+	Host string `config:"host" env:"IP_WEBSERVICE_URL" default:"localhost"`
+	Port int    `config:"port" env:"PORT" default:"3000"`
+	// Entorno de ejecución (NODE_ENV), se guarda en cada log
+	Environment string `config:"environment" env:"NODE_ENV"`
+	// Tiempo máximo para terminar las peticiones en curso al apagar el servidor
+	ShutdownTimeout time.Duration `config:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT" default:"30s"`
+}
+
+// MongoConfig es la conexión a MongoDB y el mantenimiento del esquema
+type MongoConfig struct {
+	URI string `config:"uri" env:"MONGODB_URI" secret:"url"`
+	// Único lugar donde se define el nombre de la base de datos
+	Database         string        `config:"database" env:"MONGODB_DATABASE" default:"actividadr"`
+	MigrateOnStart   bool          `config:"migrateOnStart" env:"MIGRATE_ON_START" default:"true"`
+	MigrationTimeout time.Duration `config:"migrationTimeout" env:"MIGRATION_TIMEOUT" default:"10m"`
+	LogRetentionDays int           `config:"logRetentionDays" env:"LOG_RETENTION_DAYS" default:"90"`
+}
+
+// AuthConfig agrupa los parámetros de autenticación y de las cuentas
+type AuthConfig struct {
+	JWTSecret string `config:"jwtSecret" env:"JWT_SECRET" secret:"true"`
+	// Token Bearer fijo que el scraper de Prometheus envía a /metrics; vacío deshabilita /metrics
+	MetricsToken string `config:"metricsToken" env:"METRICS_TOKEN" secret:"true"`
+
+	// Política de bloqueo por contraseñas fallidas
+	LoginMaxFailedAttempts int           `config:"loginMaxFailedAttempts" env:"LOGIN_MAX_FAILED_ATTEMPTS" default:"5"`
+	LoginLockoutDuration   time.Duration `config:"loginLockoutDuration" env:"LOGIN_LOCKOUT_DURATION" default:"15m"`
+	LoginDelayBase         time.Duration `config:"loginDelayBase" env:"LOGIN_DELAY_BASE" default:"1s"`
+
+	PasswordResetURL           string        `config:"passwordResetUrl" env:"PASSWORD_RESET_URL" default:"http://localhost:3000/reset-password"`
+	EmailVerificationURL       string        `config:"emailVerificationUrl" env:"EMAIL_VERIFICATION_URL" default:"http://localhost:3000/verify-email"`
+	VerificationResendInterval time.Duration `config:"verificationResendInterval" env:"VERIFICATION_RESEND_INTERVAL" default:"1m"`
+	UnverifiedAccountTTL       time.Duration `config:"unverifiedAccountTtl" env:"UNVERIFIED_ACCOUNT_TTL" default:"168h"`
+}
+
+// MailConfig es la configuración del envío de correos
+type MailConfig struct {
+	// "smtp", "file" o "console" (solo para desarrollo: no se admite con NODE_ENV=production)
+	Driver       string `config:"driver" env:"MAIL_DRIVER" default:"console"`
+	From         string `config:"from" env:"MAIL_FROM" default:"no-reply@actividadr.local"`
+	SMTPHost     string `config:"smtpHost" env:"SMTP_HOST"`
+	SMTPPort     int    `config:"smtpPort" env:"SMTP_PORT" default:"587"`
+	SMTPUser     string `config:"smtpUser" env:"SMTP_USER"`
+	SMTPPassword string `config:"smtpPassword" env:"SMTP_PASSWORD" secret:"true"`
+	// Archivo de destino del driver "file"
+	File string `config:"file" env:"MAIL_FILE" default:"log/mail.log"`
+}
+
+// PasswordConfig es la política de contraseñas y los parámetros de su hash
+type PasswordConfig struct {
+	MinLength     int  `config:"minLength" env:"PASSWORD_MIN_LENGTH" default:"8"`
+	MaxLength     int  `config:"maxLength" env:"PASSWORD_MAX_LENGTH" default:"128"`
+	RequireUpper  bool `config:"requireUpper" env:"PASSWORD_REQUIRE_UPPER" default:"true"`
+	RequireLower  bool `config:"requireLower" env:"PASSWORD_REQUIRE_LOWER" default:"true"`
+	RequireDigit  bool `config:"requireDigit" env:"PASSWORD_REQUIRE_DIGIT" default:"true"`
+	RequireSymbol bool `config:"requireSymbol" env:"PASSWORD_REQUIRE_SYMBOL" default:"false"`
+	// Archivo opcional con hashes SHA-1 de contraseñas filtradas
+	BreachedHashesFile string `config:"breachedHashesFile" env:"PASSWORD_BREACHED_HASHES_FILE"`
+
+	// "argon2id" o "bcrypt"; los hashes existentes se migran al iniciar sesión
+	HashAlgorithm string `config:"hashAlgorithm" env:"PASSWORD_HASH_ALGORITHM" default:"argon2id"`
+	BcryptCost    int    `config:"bcryptCost" env:"BCRYPT_COST" default:"10"`
+	// Valores por defecto de Argon2id según la recomendación de OWASP (19 MiB, 2 iteraciones)
+	Argon2Time      int `config:"argon2Time" env:"ARGON2_TIME" default:"2"`
+	Argon2MemoryKiB int `config:"argon2MemoryKib" env:"ARGON2_MEMORY_KIB" default:"19456"`
+	Argon2Threads   int `config:"argon2Threads" env:"ARGON2_THREADS" default:"1"`
+}
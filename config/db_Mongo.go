@@ -4,65 +4,45 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var MongoClient *mongo.Client
-var MongoDB *mongo.Database
-
-// InitMongoDB establece la conexión con MongoDB Atlas
-func InitMongoDB() error {
-	// Cargar variables de entorno
-	if err := godotenv.Load(); err != nil {
-		log.Println("No se pudo cargar el archivo .env (se asumirá entorno de producción)")
-	}
-
-	mongoURI := os.Getenv("MONGODB_URI")
-	if mongoURI == "" {
-		return fmt.Errorf("falta la variable de entorno MONGODB_URI")
-	}
-
+// ConnectMongo establece la conexión con MongoDB Atlas y devuelve el cliente y la base de datos configurada
+func ConnectMongo(cfg MongoConfig, opts ...*options.ClientOptions) (*mongo.Client, *mongo.Database, error) {
 	// Contexto con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Opciones de conexión
-	clientOpts := options.Client().ApplyURI(mongoURI)
-	client, err := mongo.Connect(ctx, clientOpts)
+	// Opciones de conexión; opts se aplican después de la URI (p. ej. el monitor de métricas)
+	clientOpts := options.Client().ApplyURI(cfg.URI)
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{clientOpts}, opts...)...)
 	if err != nil {
-		return fmt.Errorf("error conectando a MongoDB: %v", err)
+		return nil, nil, fmt.Errorf("error conectando a MongoDB: %v", err)
 	}
 
 	// Verificar la conexión
 	if err = client.Ping(ctx, nil); err != nil {
-		return fmt.Errorf("no se pudo hacer ping a MongoDB: %v", err)
+		client.Disconnect(context.Background())
+		return nil, nil, fmt.Errorf("no se pudo hacer ping a MongoDB: %v", err)
 	}
 
-	MongoClient = client
-	MongoDB = client.Database("actividadr")
-
 	log.Println("Conexión exitosa con MongoDB Atlas")
-	return nil
+	return client, client.Database(cfg.Database), nil
 }
 
 // CloseMongo cierra la conexión con MongoDB
-func CloseMongo() {
-	if MongoClient != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := MongoClient.Disconnect(ctx); err != nil {
-			log.Fatalf("Error al cerrar conexión con MongoDB: %v", err)
-		}
-		log.Println("Conexión con MongoDB cerrada")
+func CloseMongo(client *mongo.Client) {
+	if client == nil {
+		return
 	}
-}
-
-// GetCollection devuelve una colección específica de la base de datos
-func GetCollection(name string) *mongo.Collection {
-	return MongoDB.Collection(name)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Disconnect(ctx); err != nil {
+		log.Printf("Error al cerrar conexión con MongoDB: %v", err)
+		return
+	}
+	log.Println("Conexión con MongoDB cerrada")
 }
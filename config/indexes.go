@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserCollation compara emails y nombres de usuario sin distinguir mayúsculas.
+// Las consultas deben usarla para aprovechar (y respetar) los índices únicos de "users".
+var UserCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// Código de MongoDB cuando un índice ya existe con otras opciones
+const indexOptionsConflictCode = 85
+
+// EnsureIndexes crea (si no existen) los índices que necesita la aplicación.
+// Los logs con más de logRetentionDays días se eliminan automáticamente.
+func EnsureIndexes(db *mongo.Database, logRetentionDays int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	indexes := map[string][]mongo.IndexModel{
+		"users": {
+			{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true).SetCollation(UserCollation)},
+			{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true).SetCollation(UserCollation)},
+			// Limpieza de cuentas sin verificar
+			{Keys: bson.D{{Key: "email_verified", Value: 1}, {Key: "date_register", Value: 1}}},
+		},
+		// Campos que agrupan las consultas de /logs y filtros por usuario, acción y URL
+		// (el rango de fechas usa el índice TTL de timestamp)
+		"logs": {
+			{Keys: bson.D{{Key: "logLevel", Value: 1}}},
+			{Keys: bson.D{{Key: "status", Value: 1}}},
+			{Keys: bson.D{{Key: "responseTime", Value: 1}}},
+			{Keys: bson.D{{Key: "email", Value: 1}, {Key: "timestamp", Value: 1}}},
+			{Keys: bson.D{{Key: "action", Value: 1}, {Key: "timestamp", Value: 1}}},
+			{Keys: bson.D{{Key: "url", Value: 1}}},
+		},
+		"refresh_tokens": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "family_id", Value: 1}}},
+			{Keys: bson.D{{Key: "email", Value: 1}}},
+			// TTL: MongoDB borra los documentos al llegar a expires_at
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"mfa_challenges": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"password_resets": {
+			{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+			{Keys: bson.D{{Key: "email", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+		"revoked_tokens": {
+			{Keys: bson.D{{Key: "jti", Value: 1}}},
+			{Keys: bson.D{{Key: "email", Value: 1}, {Key: "all", Value: 1}}},
+			{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+		},
+	}
+
+	for name, models := range indexes {
+		if _, err := db.Collection(name).Indexes().CreateMany(ctx, models); err != nil {
+			return fmt.Errorf("error creando índices de %s: %v", name, err)
+		}
+	}
+
+	if err := ensureLogRetention(ctx, db, logRetentionDays); err != nil {
+		return err
+	}
+
+	log.Println("Índices de MongoDB verificados")
+	return nil
+}
+
+// ensureLogRetention crea el índice TTL de retención de logs.
+// Si la retención cambió, se actualiza el índice existente en lugar de recrearlo.
+func ensureLogRetention(ctx context.Context, db *mongo.Database, days int) error {
+	if days <= 0 {
+		log.Printf("LOG_RETENTION_DAYS debe ser mayor que 0 (%d), se usan 90 días", days)
+		days = 90
+	}
+	seconds := int32(days * 24 * 60 * 60)
+
+	_, err := db.Collection("logs").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "timestamp", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(seconds),
+	})
+
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == indexOptionsConflictCode {
+		err = db.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: "logs"},
+			{Key: "index", Value: bson.D{
+				{Key: "keyPattern", Value: bson.D{{Key: "timestamp", Value: 1}}},
+				{Key: "expireAfterSeconds", Value: seconds},
+			}},
+		}).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("error creando el índice de retención de logs: %v", err)
+	}
+	return nil
+}
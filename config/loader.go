@@ -0,0 +1,412 @@
+// ./config/loader.go
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// Variable de entorno con la ruta del archivo de configuración (YAML o JSON, opcional)
+const configFileEnv = "CONFIG_FILE"
+
+// Longitud mínima de JWT_SECRET: 32 bytes = 256 bits, el tamaño de clave de HS256
+const minJWTSecretLength = 32
+
+// Longitud mínima de METRICS_TOKEN, que no caduca y se envía en cada scrape
+const minMetricsTokenLength = 32
+
+// Límites de los parámetros de Argon2id: más iteraciones o memoria bloquearían cada login
+const (
+	maxArgon2Time      = 20
+	maxArgon2MemoryKiB = 4 * 1024 * 1024
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load construye la configuración por capas, de menor a mayor prioridad:
+// valores por defecto < archivo CONFIG_FILE < archivo .env < variables de entorno.
+// Devuelve todos los errores de validación juntos.
+func Load() (*Config, error) {
+	// .env es opcional y no sobrescribe las variables ya definidas en el entorno
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error al leer el archivo .env: %v", err)
+	}
+
+	cfg := &Config{}
+	if err := applyDefaults(cfg); err != nil {
+		return nil, err
+	}
+
+	if path := os.Getenv(configFileEnv); path != "" {
+		if err := applyFile(cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate comprueba que la configuración permita arrancar el servidor
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("PORT debe estar entre 1 y 65535 (%d)", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("SHUTDOWN_TIMEOUT debe ser mayor que 0 (%s)", c.Server.ShutdownTimeout)
+	}
+
+	if c.Mongo.URI == "" {
+		fail("falta MONGODB_URI")
+	} else if u, err := url.Parse(c.Mongo.URI); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+		fail("MONGODB_URI debe empezar por mongodb:// o mongodb+srv://")
+	}
+	if c.Mongo.Database == "" {
+		fail("MONGODB_DATABASE no puede estar vacío")
+	}
+	if c.Mongo.MigrationTimeout <= 0 {
+		fail("MIGRATION_TIMEOUT debe ser mayor que 0 (%s)", c.Mongo.MigrationTimeout)
+	}
+	if c.Mongo.LogRetentionDays <= 0 {
+		fail("LOG_RETENTION_DAYS debe ser mayor que 0 (%d)", c.Mongo.LogRetentionDays)
+	}
+
+	if c.Auth.JWTSecret == "" {
+		fail("falta JWT_SECRET")
+	} else if len(c.Auth.JWTSecret) < minJWTSecretLength {
+		fail("JWT_SECRET debe tener al menos %d caracteres (tiene %d)", minJWTSecretLength, len(c.Auth.JWTSecret))
+	}
+	if c.Auth.MetricsToken != "" && len(c.Auth.MetricsToken) < minMetricsTokenLength {
+		fail("METRICS_TOKEN debe tener al menos %d caracteres (tiene %d)", minMetricsTokenLength, len(c.Auth.MetricsToken))
+	}
+	if c.Auth.LoginMaxFailedAttempts < 0 {
+		fail("LOGIN_MAX_FAILED_ATTEMPTS no puede ser negativo (%d)", c.Auth.LoginMaxFailedAttempts)
+	}
+	if c.Auth.LoginLockoutDuration <= 0 {
+		fail("LOGIN_LOCKOUT_DURATION debe ser mayor que 0 (%s)", c.Auth.LoginLockoutDuration)
+	}
+	if c.Auth.LoginDelayBase < 0 {
+		fail("LOGIN_DELAY_BASE no puede ser negativo (%s)", c.Auth.LoginDelayBase)
+	}
+	if c.Auth.VerificationResendInterval < 0 {
+		fail("VERIFICATION_RESEND_INTERVAL no puede ser negativo (%s)", c.Auth.VerificationResendInterval)
+	}
+	if c.Auth.UnverifiedAccountTTL <= 0 {
+		fail("UNVERIFIED_ACCOUNT_TTL debe ser mayor que 0 (%s)", c.Auth.UnverifiedAccountTTL)
+	}
+	if !isHTTPURL(c.Auth.PasswordResetURL) {
+		fail("PASSWORD_RESET_URL debe ser una URL http(s) absoluta (%q)", c.Auth.PasswordResetURL)
+	}
+	if !isHTTPURL(c.Auth.EmailVerificationURL) {
+		fail("EMAIL_VERIFICATION_URL debe ser una URL http(s) absoluta (%q)", c.Auth.EmailVerificationURL)
+	}
+
+	switch strings.ToLower(c.Mail.Driver) {
+	case "", "console":
+		// Imprime los enlaces de verificación y restablecimiento (con su token) por stdout
+		if strings.EqualFold(c.Server.Environment, "production") {
+			fail("MAIL_DRIVER=console solo se permite fuera de producción (NODE_ENV=%s)", c.Server.Environment)
+		}
+	case "file":
+		if c.Mail.File == "" {
+			fail("MAIL_FILE es obligatorio con MAIL_DRIVER=file")
+		}
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			fail("SMTP_HOST es obligatorio con MAIL_DRIVER=smtp")
+		}
+		if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+			fail("SMTP_PORT debe estar entre 1 y 65535 (%d)", c.Mail.SMTPPort)
+		}
+	default:
+		fail("MAIL_DRIVER desconocido: %s (smtp, file o console)", c.Mail.Driver)
+	}
+
+	if c.Password.MinLength < 1 {
+		fail("PASSWORD_MIN_LENGTH debe ser mayor que 0 (%d)", c.Password.MinLength)
+	}
+	if c.Password.MaxLength < 0 {
+		fail("PASSWORD_MAX_LENGTH no puede ser negativo (%d)", c.Password.MaxLength)
+	} else if c.Password.MaxLength > 0 && c.Password.MaxLength < c.Password.MinLength {
+		fail("PASSWORD_MAX_LENGTH (%d) no puede ser menor que PASSWORD_MIN_LENGTH (%d)", c.Password.MaxLength, c.Password.MinLength)
+	}
+
+	switch strings.ToLower(c.Password.HashAlgorithm) {
+	case "argon2id":
+		// argon2.IDKey falla con 0 iteraciones o 0 hilos, y necesita al menos 8 KiB por hilo
+		if c.Password.Argon2Time < 1 || c.Password.Argon2Time > maxArgon2Time {
+			fail("ARGON2_TIME debe estar entre 1 y %d (%d)", maxArgon2Time, c.Password.Argon2Time)
+		}
+		if c.Password.Argon2Threads < 1 || c.Password.Argon2Threads > 255 {
+			fail("ARGON2_THREADS debe estar entre 1 y 255 (%d)", c.Password.Argon2Threads)
+		}
+		if minMemory := 8 * c.Password.Argon2Threads; c.Password.Argon2MemoryKiB < minMemory || c.Password.Argon2MemoryKiB > maxArgon2MemoryKiB {
+			fail("ARGON2_MEMORY_KIB debe estar entre %d y %d (%d)", minMemory, maxArgon2MemoryKiB, c.Password.Argon2MemoryKiB)
+		}
+	case "bcrypt":
+		if c.Password.BcryptCost < bcrypt.MinCost || c.Password.BcryptCost > bcrypt.MaxCost {
+			fail("BCRYPT_COST debe estar entre %d y %d (%d)", bcrypt.MinCost, bcrypt.MaxCost, c.Password.BcryptCost)
+		}
+	default:
+		fail("PASSWORD_HASH_ALGORITHM desconocido: %s (argon2id o bcrypt)", c.Password.HashAlgorithm)
+	}
+
+	if c.LogDir == "" {
+		fail("LOG_DIR no puede estar vacío")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted devuelve la configuración efectiva, una clave por línea, sin secretos
+func (c *Config) Redacted() string {
+	var b strings.Builder
+	_ = walkFields(reflect.ValueOf(c).Elem(), "", func(f field) error {
+		value := fmt.Sprint(f.value.Interface())
+		switch f.secret {
+		case "true":
+			if value != "" {
+				value = "********"
+			}
+		case "url":
+			value = redactURL(value)
+		}
+		fmt.Fprintf(&b, "  %s = %s\n", f.key, value)
+		return nil
+	})
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// field es un campo hoja de Config con sus etiquetas
+type field struct {
+	value reflect.Value
+	// Ruta en el archivo de configuración, p. ej. "mongo.uri"
+	key    string
+	env    string
+	def    string
+	secret string
+}
+
+// walkFields recorre los campos hoja de v (entrando en los structs anidados)
+func walkFields(v reflect.Value, prefix string, fn func(f field) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct {
+			if err := walkFields(fv, key, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := fn(field{
+			value:  fv,
+			key:    key,
+			env:    sf.Tag.Get("env"),
+			def:    sf.Tag.Get("default"),
+			secret: sf.Tag.Get("secret"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyDefaults(cfg *Config) error {
+	return walkFields(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		if f.def == "" {
+			return nil
+		}
+		if err := setField(f.value, f.def); err != nil {
+			return fmt.Errorf("valor por defecto inválido para %s: %v", f.key, err)
+		}
+		return nil
+	})
+}
+
+// applyFile aplica un archivo YAML (.yaml, .yml) o JSON (.json). Las claves
+// desconocidas son un error para no ignorar erratas en silencio.
+func applyFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error al leer %s: %v", configFileEnv, err)
+	}
+
+	raw := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("%s debe ser .yaml, .yml o .json: %s", configFileEnv, path)
+	}
+	if err != nil {
+		return fmt.Errorf("error al interpretar %s: %v", path, err)
+	}
+
+	values := map[string]interface{}{}
+	if err := flatten(raw, "", values); err != nil {
+		return fmt.Errorf("error en %s: %v", path, err)
+	}
+
+	err = walkFields(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		value, ok := values[f.key]
+		if !ok {
+			return nil
+		}
+		delete(values, f.key)
+		if err := setField(f.value, scalarString(value)); err != nil {
+			return fmt.Errorf("valor inválido para %s en %s: %v", f.key, path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(values) > 0 {
+		unknown := make([]string, 0, len(values))
+		for key := range values {
+			unknown = append(unknown, key)
+		}
+		sort.Strings(unknown)
+		return fmt.Errorf("claves desconocidas en %s: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func applyEnv(cfg *Config) error {
+	var errs []error
+	_ = walkFields(reflect.ValueOf(cfg).Elem(), "", func(f field) error {
+		if f.env == "" {
+			return nil
+		}
+		value := os.Getenv(f.env)
+		if value == "" {
+			return nil
+		}
+		if err := setField(f.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("valor inválido para %s (%q): %v", f.env, value, err))
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// flatten convierte los mapas anidados del archivo en claves con puntos ("mongo.uri")
+func flatten(raw map[string]interface{}, prefix string, out map[string]interface{}) error {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := flatten(v, key, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			return fmt.Errorf("%s: no se admiten listas", key)
+		default:
+			out[key] = v
+		}
+	}
+	return nil
+}
+
+// scalarString pasa un valor del archivo a texto para interpretarlo igual que una variable de entorno
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		// JSON decodifica todos los números como float64
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// setField interpreta raw según el tipo del campo
+func setField(v reflect.Value, raw string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("se esperaba un número entero")
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("se esperaba true o false")
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("tipo no soportado: %s", v.Type())
+	}
+	return nil
+}
+
+// redactURL oculta la contraseña de una URI de conexión
+func redactURL(value string) string {
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return "********"
+	}
+	return u.Redacted()
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
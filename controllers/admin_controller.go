@@ -0,0 +1,381 @@
+// ./controllers/admin_controller.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Tamaño de página por defecto y máximo del listado de usuarios
+const (
+	defaultUsersPageSize = 20
+	maxUsersPageSize     = 100
+)
+
+// ListUsers lista usuarios paginados. Filtros opcionales: registeredFrom/registeredTo,
+// lastLoginFrom/lastLoginTo (RFC 3339 o AAAA-MM-DD), mfaEnabled (true/false) y q
+// (búsqueda por email o nombre de usuario).
+func (h *Handler) ListUsers(c *fiber.Ctx) error {
+	adminEmail := currentUserEmail(c)
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", defaultUsersPageSize)
+	if page < 1 || limit < 1 || limit > maxUsersPageSize {
+		h.actions.LogAction(adminEmail, "admin-listUsers-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Paginación inválida (page >= 1, 1 <= limit <= %d)", maxUsersPageSize),
+		})
+	}
+
+	var filter repositories.UserFilter
+	var err error
+	filter.RegisteredFrom, filter.RegisteredTo, err = dateRangeParams(c.Query("registeredFrom"), c.Query("registeredTo"))
+	if err != nil {
+		h.actions.LogAction(adminEmail, "admin-listUsers-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	filter.LastLoginFrom, filter.LastLoginTo, err = dateRangeParams(c.Query("lastLoginFrom"), c.Query("lastLoginTo"))
+	if err != nil {
+		h.actions.LogAction(adminEmail, "admin-listUsers-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if value := c.Query("mfaEnabled"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			h.actions.LogAction(adminEmail, "admin-listUsers-error", "error")(c)
+			return c.Status(400).JSON(fiber.Map{"error": "mfaEnabled debe ser true o false"})
+		}
+		filter.MFAEnabled = &enabled
+	}
+
+	filter.Search = c.Query("q")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users, total, err := h.users.List(ctx, filter, int64((page-1)*limit), int64(limit))
+	if err != nil {
+		h.actions.LogAction(adminEmail, "admin-listUsers-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al listar los usuarios"})
+	}
+
+	h.actions.LogAction(adminEmail, "admin-listUsers", "info")(c)
+	return c.JSON(fiber.Map{
+		"users": users,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+// GetUser devuelve un usuario sin sus campos privados
+func (h *Handler) GetUser(c *fiber.Ctx) error {
+	adminEmail := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, emailParam(c))
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-getUser-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-getUser-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener el usuario"})
+	}
+
+	h.actions.LogAction(adminEmail, "admin-getUser", "info")(c)
+	return c.JSON(user)
+}
+
+// DisableUser deshabilita la cuenta y cierra todas sus sesiones
+func (h *Handler) DisableUser(c *fiber.Ctx) error {
+	return h.setUserDisabled(c, true, "admin-disableUser")
+}
+
+// EnableUser vuelve a habilitar una cuenta deshabilitada
+func (h *Handler) EnableUser(c *fiber.Ctx) error {
+	return h.setUserDisabled(c, false, "admin-enableUser")
+}
+
+func (h *Handler) setUserDisabled(c *fiber.Ctx, disabled bool, action string) error {
+	adminEmail := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Se resuelve el usuario para usar el email guardado: la búsqueda no distingue
+	// mayúsculas, pero la revocación de sesiones compara el email exacto
+	user, err := h.users.FindByEmail(ctx, emailParam(c))
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el usuario"})
+	}
+	email := user.Email
+
+	if email == adminEmail {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "No puedes modificar tu propia cuenta"})
+	}
+
+	err = h.users.SetDisabled(ctx, email, disabled)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el usuario"})
+	}
+
+	if disabled {
+		if err := h.revokeUserSessions(ctx, email); err != nil {
+			h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el usuario"})
+		}
+	}
+
+	h.actions.LogAction(adminEmail, action, "info")(c)
+	return c.JSON(fiber.Map{
+		"message":  "Usuario actualizado",
+		"email":    email,
+		"disabled": disabled,
+	})
+}
+
+// ResetUserMFA deshabilita MFA del usuario para que vuelva a enrolarse
+func (h *Handler) ResetUserMFA(c *fiber.Ctx) error {
+	adminEmail := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, emailParam(c))
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-resetMfa-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-resetMfa-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer MFA"})
+	}
+	email := user.Email
+
+	err = h.users.DisableMFA(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-resetMfa-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-resetMfa-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer MFA"})
+	}
+
+	if err := h.revokeUserSessions(ctx, email); err != nil {
+		h.actions.LogAction(adminEmail, "admin-resetMfa-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer MFA"})
+	}
+
+	h.actions.LogAction(adminEmail, "admin-resetMfa", "info")(c)
+	return c.JSON(fiber.Map{
+		"message": "MFA restablecido",
+		"email":   email,
+	})
+}
+
+// ForceUserPasswordReset obliga al usuario a restablecer su contraseña: bloquea el
+// login con la contraseña actual, cierra sus sesiones y le envía un enlace de restablecimiento
+func (h *Handler) ForceUserPasswordReset(c *fiber.Ctx) error {
+	adminEmail := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, emailParam(c))
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-forcePasswordReset-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-forcePasswordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al forzar el restablecimiento"})
+	}
+	email := user.Email
+
+	err = h.users.SetPasswordResetRequired(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-forcePasswordReset-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-forcePasswordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al forzar el restablecimiento"})
+	}
+
+	if err := h.revokeUserSessions(ctx, email); err != nil {
+		h.actions.LogAction(adminEmail, "admin-forcePasswordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al forzar el restablecimiento"})
+	}
+
+	if err := h.sendPasswordReset(ctx, email); err != nil {
+		log.Println("Error al enviar el correo de restablecimiento:", err)
+		h.actions.LogAction(adminEmail, "admin-forcePasswordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al enviar el correo de restablecimiento"})
+	}
+
+	h.actions.LogAction(adminEmail, "admin-forcePasswordReset", "info")(c)
+	return c.JSON(fiber.Map{
+		"message": "Se envió un enlace de restablecimiento al usuario",
+		"email":   email,
+	})
+}
+
+// SetUserRole asigna un rol a un usuario
+func (h *Handler) SetUserRole(c *fiber.Ctx) error {
+	type RoleRequest struct {
+		Role string `json:"role"`
+	}
+
+	adminEmail := currentUserEmail(c)
+
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil || !utils.IsValidRole(req.Role) {
+		h.actions.LogAction(adminEmail, "admin-setRole-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Rol inválido"})
+	}
+
+	return h.updateUserRole(c, adminEmail, req.Role, "admin-setRole")
+}
+
+// RemoveUserRole retira el rol especial de un usuario y lo deja como "user"
+func (h *Handler) RemoveUserRole(c *fiber.Ctx) error {
+	return h.updateUserRole(c, currentUserEmail(c), utils.RoleUser, "admin-removeRole")
+}
+
+// updateUserRole guarda el rol del usuario de la ruta y cierra sus sesiones para que
+// sus tokens vigentes no conserven el rol anterior
+func (h *Handler) updateUserRole(c *fiber.Ctx, adminEmail string, role string, action string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, emailParam(c))
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el rol"})
+	}
+	email := user.Email
+
+	if email == adminEmail {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "No puedes modificar tu propio rol"})
+	}
+
+	err = h.users.SetRole(ctx, email, role)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el rol"})
+	}
+
+	if err := h.revokeUserSessions(ctx, email); err != nil {
+		h.actions.LogAction(adminEmail, action+"-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el rol"})
+	}
+
+	h.actions.LogAction(adminEmail, action, "info")(c)
+	return c.JSON(fiber.Map{
+		"message": "Rol actualizado",
+		"email":   email,
+		"role":    role,
+	})
+}
+
+// UnlockUser quita el bloqueo por intentos fallidos de login y de OTP
+func (h *Handler) UnlockUser(c *fiber.Ctx) error {
+	adminEmail := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, emailParam(c))
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-unlockUser-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-unlockUser-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al desbloquear el usuario"})
+	}
+	email := user.Email
+
+	err = h.users.Unlock(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(adminEmail, "admin-unlockUser-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(adminEmail, "admin-unlockUser-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al desbloquear el usuario"})
+	}
+
+	h.actions.LogAction(adminEmail, "admin-unlockUser", "info")(c)
+	return c.JSON(fiber.Map{
+		"message": "Usuario desbloqueado",
+		"email":   email,
+	})
+}
+
+// emailParam devuelve el parámetro :email de la ruta ya decodificado (p. ej. %40 -> @)
+func emailParam(c *fiber.Ctx) string {
+	email := c.Params("email")
+	if decoded, err := url.PathUnescape(email); err == nil {
+		return decoded
+	}
+	return email
+}
+
+// dateRangeParams interpreta dos fechas opcionales que delimitan un rango (ambos extremos
+// incluidos). Un to con solo la fecha abarca ese día completo.
+func dateRangeParams(from string, to string) (*time.Time, *time.Time, error) {
+	var fromTime, toTime *time.Time
+	if from != "" {
+		t, err := parseDateParam(from)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fecha inválida: %s", from)
+		}
+		fromTime = &t
+	}
+	if to != "" {
+		t, err := parseDateParam(to)
+		if err != nil {
+			return nil, nil, fmt.Errorf("fecha inválida: %s", to)
+		}
+		if len(to) == len(dateOnlyLayout) {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		toTime = &t
+	}
+	return fromTime, toTime, nil
+}
+
+// Formato de las fechas sin hora en la query
+const dateOnlyLayout = "2006-01-02"
+
+// parseDateParam acepta RFC 3339 o solo la fecha (AAAA-MM-DD)
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(dateOnlyLayout, value)
+}
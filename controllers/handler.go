@@ -0,0 +1,66 @@
+// ./controllers/handler.go
+
+package controllers
+
+import (
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/mailer"
+	"github.com/Ana-Gabs/actividadr-back/metrics"
+	"github.com/Ana-Gabs/actividadr-back/password"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Handler agrupa los handlers HTTP y las dependencias que comparten.
+// Cada instancia es independiente, así que pueden coexistir varias (p. ej. en pruebas).
+type Handler struct {
+	cfg         *config.Config
+	db          *mongo.Database
+	users       repositories.UserRepository
+	tokens      TokenStores
+	passwords   password.Passwords
+	mailer      mailer.Mailer
+	sends       *mailer.Queue
+	revocations *utils.RevocationStore
+	actions     *utils.ActionLogger
+	metrics     *metrics.Metrics
+	health      HealthProbes
+}
+
+// TokenStores agrupa los repositorios de los tokens de sesión y de un solo uso
+type TokenStores struct {
+	RefreshTokens  repositories.RefreshTokenRepository
+	MFAChallenges  repositories.MFAChallengeRepository
+	PasswordResets repositories.PasswordResetRepository
+}
+
+// HealthProbes son las comprobaciones de la instancia que los handlers de salud
+// no pueden hacer por sí mismos
+type HealthProbes struct {
+	// Ready es false antes de que el servidor escuche y desde que empieza el apagado
+	Ready func() bool
+	// LogSink comprueba que se puede escribir en los archivos de log
+	LogSink func() error
+	// Momento de arranque, para calcular el uptime
+	StartedAt time.Time
+}
+
+// NewHandler crea los handlers con sus dependencias
+func NewHandler(cfg *config.Config, db *mongo.Database, users repositories.UserRepository, tokens TokenStores, passwords password.Passwords, m mailer.Mailer, sends *mailer.Queue, revocations *utils.RevocationStore, actions *utils.ActionLogger, metrics *metrics.Metrics, health HealthProbes) *Handler {
+	return &Handler{
+		cfg:         cfg,
+		db:          db,
+		users:       users,
+		tokens:      tokens,
+		passwords:   passwords,
+		mailer:      m,
+		sends:       sends,
+		revocations: revocations,
+		actions:     actions,
+		metrics:     metrics,
+		health:      health,
+	}
+}
@@ -0,0 +1,146 @@
+// ./controllers/handler_test.go
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/metrics"
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"github.com/Ana-Gabs/actividadr-back/password"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// testPassword cumple la política de contraseñas de las pruebas
+const testPassword = "Correcta-123"
+
+// testServer es un Handler sobre repositorios en memoria y las rutas que usan las pruebas
+type testServer struct {
+	handler *Handler
+	users   *repositories.MemoryUserRepository
+	tokens  TokenStores
+	app     *fiber.App
+}
+
+// newTestServer crea el servidor de pruebas. El login se bloquea al tercer fallo y sin
+// espera progresiva, para que las pruebas no dependan del reloj.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			JWTSecret:              strings.Repeat("s", 32),
+			LoginMaxFailedAttempts: 3,
+			LoginLockoutDuration:   15 * time.Minute,
+		},
+	}
+	// Parámetros de Argon2id mínimos para que las pruebas sean rápidas
+	passwords := password.Passwords{
+		Policy: password.Policy{MinLength: 8, MaxLength: 128},
+		Hash: password.HashParams{
+			Algorithm:        password.AlgorithmArgon2id,
+			Argon2Time:       1,
+			Argon2MemoryKiB:  64,
+			Argon2Threads:    1,
+			Argon2KeyLength:  password.DefaultArgon2KeyLength,
+			Argon2SaltLength: password.DefaultArgon2SaltLength,
+		},
+	}
+	users := repositories.NewMemoryUserRepository()
+	tokens := TokenStores{
+		RefreshTokens:  repositories.NewMemoryRefreshTokenRepository(),
+		MFAChallenges:  repositories.NewMemoryMFAChallengeRepository(),
+		PasswordResets: repositories.NewMemoryPasswordResetRepository(),
+	}
+	actions := utils.NewActionLogger(nil, nil, "test")
+
+	h := NewHandler(cfg, nil, users, tokens, passwords, nil, nil, nil, actions, metrics.New(), HealthProbes{})
+
+	app := fiber.New()
+	app.Use(actions.Middleware())
+	app.Post("/login", h.Login)
+	app.Post("/verify-otp", h.VerifyOtp)
+	app.Post("/refresh-token", h.RefreshToken)
+
+	return &testServer{handler: h, users: users, tokens: tokens, app: app}
+}
+
+// createUser guarda un usuario verificado con testPassword; modify ajusta el resto de campos
+func (s *testServer) createUser(t *testing.T, email string, username string, modify func(u *models.User)) *models.User {
+	t.Helper()
+
+	hash, err := s.handler.passwords.Hash.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("hash de la contraseña: %v", err)
+	}
+	user := &models.User{
+		Email:        email,
+		Username:     username,
+		Password:     hash,
+		Role:         utils.RoleUser,
+		DateRegister: time.Now(),
+	}
+	if modify != nil {
+		modify(user)
+	}
+	if err := s.users.Create(context.Background(), user); err != nil {
+		t.Fatalf("crear usuario: %v", err)
+	}
+	return user
+}
+
+// post envía body como JSON y devuelve el código de estado y la respuesta decodificada
+func (s *testServer) post(t *testing.T, path string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("codificar la petición: %v", err)
+	}
+	req := httptest.NewRequest("POST", path, strings.NewReader(string(payload)))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("POST %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("leer la respuesta: %v", err)
+	}
+	result := map[string]interface{}{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &result); err != nil {
+			t.Fatalf("decodificar la respuesta de %s (%s): %v", path, raw, err)
+		}
+	}
+	return resp.StatusCode, result
+}
+
+// login inicia sesión con emailOrUsername y la contraseña indicada
+func (s *testServer) login(t *testing.T, emailOrUsername string, plain string) (int, map[string]interface{}) {
+	t.Helper()
+	return s.post(t, "/login", fiber.Map{"emailOrUsername": emailOrUsername, "password": plain})
+}
+
+// stringField devuelve el campo de texto de la respuesta o falla la prueba
+func stringField(t *testing.T, body map[string]interface{}, field string) string {
+	t.Helper()
+
+	value, ok := body[field].(string)
+	if !ok || value == "" {
+		t.Fatalf("la respuesta no tiene %q: %v", field, body)
+	}
+	return value
+}
@@ -0,0 +1,149 @@
+// ./controllers/health_controller.go
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Tiempo máximo de cada comprobación de /readyz
+const readinessCheckTimeout = 2 * time.Second
+
+// healthCheck es el resultado de una comprobación de disponibilidad
+type healthCheck struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Healthz indica que el proceso está vivo; no consulta dependencias
+func (h *Handler) Healthz(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// Readyz indica si la instancia puede recibir tráfico: MongoDB responde, los logs
+// admiten escritura y el servidor no se está apagando. Devuelve 503 si algo falla.
+// Es pública, así que no incluye el detalle de los errores (ver GetHealthDetails).
+func (h *Handler) Readyz(c *fiber.Ctx) error {
+	ready, checks := h.readinessChecks()
+	for name, check := range checks {
+		check.Error = ""
+		checks[name] = check
+	}
+	return c.Status(readinessStatusCode(ready)).JSON(fiber.Map{
+		"status": readinessStatus(ready),
+		"checks": checks,
+	})
+}
+
+// GetHealthDetails es la variante de /readyz para administradores, con información
+// de compilación y uptime
+func (h *Handler) GetHealthDetails(c *fiber.Ctx) error {
+	adminEmail := currentUserEmail(c)
+
+	ready, checks := h.readinessChecks()
+	uptime := time.Since(h.health.StartedAt)
+
+	if ready {
+		h.actions.LogAction(adminEmail, "admin-health", "info")(c)
+	} else {
+		h.actions.LogAction(adminEmail, "admin-health-error", "error")(c)
+	}
+	return c.Status(readinessStatusCode(ready)).JSON(fiber.Map{
+		"status":        readinessStatus(ready),
+		"checks":        checks,
+		"build":         buildInfo(),
+		"startedAt":     h.health.StartedAt,
+		"uptime":        uptime.Round(time.Second).String(),
+		"uptimeSeconds": int64(uptime.Seconds()),
+		"goroutines":    runtime.NumGoroutine(),
+	})
+}
+
+// readinessChecks ejecuta las comprobaciones e indica si todas pasaron
+func (h *Handler) readinessChecks() (bool, map[string]healthCheck) {
+	checks := map[string]healthCheck{
+		"mongo": runHealthCheck(func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), readinessCheckTimeout)
+			defer cancel()
+			return h.db.Client().Ping(ctx, readpref.Primary())
+		}),
+		"logs": runHealthCheck(h.health.LogSink),
+		"shutdown": runHealthCheck(func() error {
+			if h.health.Ready != nil && !h.health.Ready() {
+				return errors.New("el servidor no acepta tráfico (arrancando o apagándose)")
+			}
+			return nil
+		}),
+	}
+
+	ready := true
+	for _, check := range checks {
+		if check.Status != "ok" {
+			ready = false
+		}
+	}
+	return ready, checks
+}
+
+// runHealthCheck mide una comprobación; una comprobación nil se da por buena
+func runHealthCheck(check func() error) healthCheck {
+	start := time.Now()
+	var err error
+	if check != nil {
+		err = check()
+	}
+	result := healthCheck{
+		Status:    "ok",
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func readinessStatus(ready bool) string {
+	if ready {
+		return "ok"
+	}
+	return "error"
+}
+
+func readinessStatusCode(ready bool) int {
+	if ready {
+		return 200
+	}
+	return 503
+}
+
+// buildInfo devuelve la versión de Go y, si el binario la incluye, la del módulo y el commit
+func buildInfo() fiber.Map {
+	info := fiber.Map{"goVersion": runtime.Version()}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info["module"] = bi.Main.Path
+	info["version"] = bi.Main.Version
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info["revision"] = setting.Value
+		case "vcs.time":
+			info["commitTime"] = setting.Value
+		case "vcs.modified":
+			info["modified"] = setting.Value == "true"
+		}
+	}
+	return info
+}
@@ -0,0 +1,106 @@
+// ./controllers/login_protection.go
+
+package controllers
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// loginLockoutPolicy define cuándo se bloquea una cuenta por contraseñas fallidas
+type loginLockoutPolicy struct {
+	// Fallos consecutivos que provocan el bloqueo completo
+	MaxFailedAttempts int
+	// Duración del bloqueo completo
+	LockoutDuration time.Duration
+	// Espera tras el primer fallo; se duplica en cada fallo siguiente (0 la desactiva)
+	DelayBase time.Duration
+}
+
+// loginLockoutPolicy devuelve la política configurada
+func (h *Handler) loginLockoutPolicy() loginLockoutPolicy {
+	return loginLockoutPolicy{
+		MaxFailedAttempts: h.cfg.Auth.LoginMaxFailedAttempts,
+		LockoutDuration:   h.cfg.Auth.LoginLockoutDuration,
+		DelayBase:         h.cfg.Auth.LoginDelayBase,
+	}
+}
+
+// loginLockedUntil devuelve hasta cuándo el usuario no puede intentar iniciar sesión
+func loginLockedUntil(user *models.User) time.Time {
+	if user.LoginLockedUntil != nil {
+		return *user.LoginLockedUntil
+	}
+	return time.Time{}
+}
+
+// recordLoginFailure incrementa el contador de fallos y aplica la espera progresiva
+// o el bloqueo completo. Indica si este fallo dejó la cuenta bloqueada.
+func (h *Handler) recordLoginFailure(ctx context.Context, email string) (bool, error) {
+	policy := h.loginLockoutPolicy()
+
+	attempts, err := h.users.IncrementLoginFailures(ctx, email)
+	if err != nil {
+		return false, err
+	}
+
+	locked := policy.MaxFailedAttempts > 0 && attempts >= policy.MaxFailedAttempts
+	wait := policy.LockoutDuration
+	if !locked {
+		if policy.DelayBase <= 0 {
+			return false, nil
+		}
+		wait = time.Duration(float64(policy.DelayBase) * math.Pow(2, float64(attempts-1)))
+		if wait <= 0 || wait > policy.LockoutDuration {
+			wait = policy.LockoutDuration
+		}
+	}
+
+	if err := h.users.SetLoginLockedUntil(ctx, email, time.Now().Add(wait)); err != nil {
+		return false, err
+	}
+	return locked, nil
+}
+
+// resetLoginFailures limpia el contador tras una contraseña correcta o un desbloqueo
+func (h *Handler) resetLoginFailures(ctx context.Context, email string) error {
+	return h.users.ResetLoginFailures(ctx, email)
+}
+
+// checkPassword verifica la contraseña con la que un usuario autenticado confirma una
+// operación sensible. Comparte contador y bloqueo con Login: un fallo cuenta como
+// intento fallido y un acierto lo limpia. Quien llama debe comprobar loginLockedUntil antes.
+func (h *Handler) checkPassword(c *fiber.Ctx, ctx context.Context, user *models.User, plain string) (bool, error) {
+	valid, _, err := h.passwords.Hash.Verify(plain, user.Password)
+	if err != nil {
+		return false, err
+	}
+	if !valid {
+		locked, err := h.recordLoginFailure(ctx, user.Email)
+		if err != nil {
+			return false, err
+		}
+		if locked {
+			h.actions.LogAction(user.Email, "login-lockout", "warn")(c)
+		}
+		return false, nil
+	}
+	return true, h.resetLoginFailures(ctx, user.Email)
+}
+
+// loginLockedResponse responde a un intento de login (o de confirmar una operación con la
+// contraseña) mientras la cuenta está bloqueada
+func (h *Handler) loginLockedResponse(c *fiber.Ctx, email string, lockedUntil time.Time) error {
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	h.actions.LogAction(email, "login-locked", "error")(c)
+	return c.Status(429).JSON(fiber.Map{
+		"error":      "Cuenta bloqueada temporalmente por intentos fallidos, intenta más tarde",
+		"retryAfter": retryAfter,
+	})
+}
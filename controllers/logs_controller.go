@@ -6,138 +6,383 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"regexp"
+	"strings"
 	"time"
 
-	"github.com/Ana-Gabs/actividadr-back/config"
-	"github.com/Ana-Gabs/actividadr-back/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+// logGroup es una fila del $group de las analíticas de logs: un valor del campo y sus logs
+type logGroup struct {
+	Value interface{} `bson:"_id"`
+	Count int         `bson:"count"`
+}
+
+// Métodos HTTP aceptados en el filtro method
+var logMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "OPTIONS": true, "CONNECT": true, "TRACE": true,
+}
+
+// statusClassPattern acepta una clase de estado HTTP: 1xx a 5xx
+var statusClassPattern = regexp.MustCompile(`^[1-5][xX][xX]$`)
+
+// logTimeRange interpreta from/to de la query (RFC 3339 o AAAA-MM-DD), ambos opcionales
+func logTimeRange(c *fiber.Ctx) (*time.Time, *time.Time, error) {
+	from, to, err := dateRangeParams(c.Query("from"), c.Query("to"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if from != nil && to != nil && from.After(*to) {
+		return nil, nil, fmt.Errorf("from debe ser anterior a to")
+	}
+	return from, to, nil
+}
+
+// logFilterParams construye el filtro común de las analíticas de logs a partir de la query:
+// from/to (RFC 3339 o AAAA-MM-DD), method, url (prefijo), email, action y status (clase, p. ej. 4xx)
+func logFilterParams(c *fiber.Ctx) (bson.M, error) {
+	filter := bson.M{}
+
+	from, to, err := logTimeRange(c)
+	if err != nil {
+		return nil, err
+	}
+	if from != nil || to != nil {
+		timestamp := bson.M{}
+		if from != nil {
+			timestamp["$gte"] = *from
+		}
+		if to != nil {
+			timestamp["$lte"] = *to
+		}
+		filter["timestamp"] = timestamp
+	}
+
+	if method := c.Query("method"); method != "" {
+		method = strings.ToUpper(method)
+		if !logMethods[method] {
+			return nil, fmt.Errorf("método HTTP inválido: %s", c.Query("method"))
+		}
+		filter["method"] = method
+	}
+
+	if prefix := c.Query("url"); prefix != "" {
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("url debe empezar por /")
+		}
+		// Regex anclada al inicio: MongoDB la resuelve como un rango del índice de url
+		filter["url"] = bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}
+	}
+
+	if email := c.Query("email"); email != "" {
+		filter["email"] = email
+	}
+
+	if action := c.Query("action"); action != "" {
+		filter["action"] = action
+	}
+
+	if class := c.Query("status"); class != "" {
+		if !statusClassPattern.MatchString(class) {
+			return nil, fmt.Errorf("status debe ser una clase de estado (1xx a 5xx): %s", class)
+		}
+		base := int(class[0]-'0') * 100
+		filter["status"] = bson.M{"$gte": base, "$lt": base + 100}
+	}
+
+	return filter, nil
+}
+
+// groupLogsBy cuenta los logs que cumplen filter por cada valor distinto de field en MongoDB.
+// Sin filtro, el $sort previo permite recorrer solo el índice del campo (ver config.EnsureIndexes)
+// sin leer los documentos; con filtro, el $match usa los índices del filtro.
+// Go solo recibe una fila por valor distinto.
+func (h *Handler) groupLogsBy(ctx context.Context, field string, filter bson.M) ([]logGroup, error) {
+	var pipeline bson.A
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	} else {
+		pipeline = append(pipeline, bson.M{"$sort": bson.M{field: 1}})
+	}
+	pipeline = append(pipeline, bson.M{"$group": bson.M{
+		"_id":   "$" + field,
+		"count": bson.M{"$sum": 1},
+	}})
+
+	cursor, err := h.db.Collection("logs").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var groups []logGroup
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetLogsByLevel agrupa los logs por nivel. Acepta los filtros de logFilterParams.
+func (h *Handler) GetLogsByLevel(c *fiber.Ctx) error {
+	filter, err := logFilterParams(c)
+	if err != nil {
+		h.actions.LogAction(currentUserEmail(c), "getLogsByLevel-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 
-func GetLogsByLevel(c *fiber.Ctx) error {
-	collection := config.GetCollection("logs")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := collection.Find(ctx, bson.M{})
+	groups, err := h.groupLogsBy(ctx, "logLevel", filter)
 	if err != nil {
-		utils.LogAction("anonymous", "getLogsByLevel-error", "error")(c)
+		h.actions.LogAction(currentUserEmail(c), "getLogsByLevel-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los logs por nivel"})
 	}
-	defer cursor.Close(ctx)
 
 	groupedByLevel := make(map[string]int)
-	for cursor.Next(ctx) {
-		var log bson.M
-		if err := cursor.Decode(&log); err != nil {
-			utils.LogAction("anonymous", "getLogsByLevel-error", "error")(c)
-			return c.Status(500).JSON(fiber.Map{"error": "Error al procesar los logs"})
-		}
-		level, ok := log["logLevel"].(string)
+	for _, group := range groups {
+		level, ok := group.Value.(string)
 		if !ok {
 			level = "unknown"
 		}
-		groupedByLevel[level]++
-	}
-
-	if err := cursor.Err(); err != nil {
-		utils.LogAction("anonymous", "getLogsByLevel-error", "error")(c)
-		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los logs por nivel"})
+		groupedByLevel[level] += group.Count
 	}
 
 	// Registrar acción
-	utils.LogAction("anonymous", "getLogsByLevel", "info")(c)
+	h.actions.LogAction(currentUserEmail(c), "getLogsByLevel", "info")(c)
 	return c.Status(200).JSON(groupedByLevel)
 }
 
-// GetLogsByResponseTime agrupa los logs por tiempo de respuesta
-func GetLogsByResponseTime(c *fiber.Ctx) error {
-	collection := config.GetCollection("logs")
+// GetLogsByResponseTime agrupa los logs por tiempo de respuesta. Acepta los filtros de logFilterParams.
+func (h *Handler) GetLogsByResponseTime(c *fiber.Ctx) error {
+	filter, err := logFilterParams(c)
+	if err != nil {
+		h.actions.LogAction(currentUserEmail(c), "getLogsByResponseTime-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Obtener todos los logs
-	cursor, err := collection.Find(ctx, bson.M{})
+	groups, err := h.groupLogsBy(ctx, "responseTime", filter)
 	if err != nil {
-		utils.LogAction("anonymous", "getLogsByResponseTime-error", "error")(c)
+		h.actions.LogAction(currentUserEmail(c), "getLogsByResponseTime-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los logs por tiempo de respuesta"})
 	}
-	defer cursor.Close(ctx)
 
-	// Agrupar logs por tiempo de respuesta (en segundos)
+	// Agrupar logs por tiempo de respuesta; los valores no numéricos cuentan como 0
 	responseTimeStats := make(map[int]int)
-	for cursor.Next(ctx) {
-		var log bson.M
-		if err := cursor.Decode(&log); err != nil {
-			utils.LogAction("anonymous", "getLogsByResponseTime-error", "error")(c)
-			return c.Status(500).JSON(fiber.Map{"error": "Error al procesar los logs"})
-		}
-		responseTime, ok := log["responseTime"].(float64)
-		if !ok {
-			responseTime = 0
+	for _, group := range groups {
+		var responseTime float64
+		switch v := group.Value.(type) {
+		case int32:
+			responseTime = float64(v)
+		case int64:
+			responseTime = float64(v)
+		case float64:
+			responseTime = v
 		}
 		rangeKey := int(math.Floor(responseTime))
-		responseTimeStats[rangeKey]++
-	}
-
-	if err := cursor.Err(); err != nil {
-		utils.LogAction("anonymous", "getLogsByResponseTime-error", "error")(c)
-		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los logs por tiempo de respuesta"})
+		responseTimeStats[rangeKey] += group.Count
 	}
 
 	// Registrar acción
-	utils.LogAction("anonymous", "getLogsByResponseTime", "info")(c)
+	h.actions.LogAction(currentUserEmail(c), "getLogsByResponseTime", "info")(c)
 	return c.Status(200).JSON(responseTimeStats)
 }
 
-// GetLogsByStatus agrupa los logs por código de estado HTTP
-func GetLogsByStatus(c *fiber.Ctx) error {
-	collection := config.GetCollection("logs")
+// GetLogsByStatus agrupa los logs por código de estado HTTP. Acepta los filtros de logFilterParams.
+func (h *Handler) GetLogsByStatus(c *fiber.Ctx) error {
+	filter, err := logFilterParams(c)
+	if err != nil {
+		h.actions.LogAction(currentUserEmail(c), "getLogsByStatus-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Obtener todos los logs
-	cursor, err := collection.Find(ctx, bson.M{})
+	groups, err := h.groupLogsBy(ctx, "status", filter)
 	if err != nil {
-		utils.LogAction("anonymous", "getLogsByStatus-error", "error")(c)
+		h.actions.LogAction(currentUserEmail(c), "getLogsByStatus-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los logs por código de estado"})
 	}
-	defer cursor.Close(ctx)
 
 	// Agrupar logs por status
 	groupedByStatus := make(map[string]int)
-	for cursor.Next(ctx) {
-		var log bson.M
-		if err := cursor.Decode(&log); err != nil {
-			utils.LogAction("anonymous", "getLogsByStatus-error", "error")(c)
-			return c.Status(500).JSON(fiber.Map{"error": "Error al procesar los logs"})
-		}
+	for _, group := range groups {
 		var status string
-		if log["status"] != nil {
-			// Convertir status a string, ya que puede ser int32 en MongoDB
-			switch v := log["status"].(type) {
-			case int32:
-				status = fmt.Sprintf("%d", v)
-			case int64:
-				status = fmt.Sprintf("%d", v)
-			case float64:
-				status = fmt.Sprintf("%d", int(v))
-			default:
-				status = "unknown"
-			}
-		} else {
+		// Convertir status a string, ya que puede ser int32 en MongoDB
+		switch v := group.Value.(type) {
+		case int32:
+			status = fmt.Sprintf("%d", v)
+		case int64:
+			status = fmt.Sprintf("%d", v)
+		case float64:
+			status = fmt.Sprintf("%d", int(v))
+		default:
 			status = "unknown"
 		}
-		groupedByStatus[status]++
-	}
-
-	if err := cursor.Err(); err != nil {
-		utils.LogAction("anonymous", "getLogsByStatus-error", "error")(c)
-		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los logs por código de estado"})
+		groupedByStatus[status] += group.Count
 	}
 
 	// Registrar acción
-	utils.LogAction("anonymous", "getLogsByStatus", "info")(c)
+	h.actions.LogAction(currentUserEmail(c), "getLogsByStatus", "info")(c)
 	return c.Status(200).JSON(groupedByStatus)
 }
+
+// Intervalos de /logs/timeseries: duración de cada bucket y rango por defecto si falta from
+var timeseriesIntervals = map[string]struct {
+	step          time.Duration
+	defaultWindow time.Duration
+}{
+	"minute": {time.Minute, time.Hour},
+	"hour":   {time.Hour, 24 * time.Hour},
+	"day":    {24 * time.Hour, 30 * 24 * time.Hour},
+}
+
+// Máximo de buckets por consulta (un día por minutos)
+const maxTimeseriesBuckets = 1440
+
+// timeseriesBucket es un intervalo de /logs/timeseries
+type timeseriesBucket struct {
+	Timestamp time.Time `json:"timestamp"`
+	Count     int       `json:"count"`
+	// Peticiones con status >= 400 o registradas con logLevel "error"
+	Errors       int                          `json:"errors"`
+	ErrorRate    float64                      `json:"errorRate"`
+	ResponseTime timeseriesLatencyPercentiles `json:"responseTime"`
+}
+
+// timeseriesLatencyPercentiles son percentiles de responseTime en ms; nil si el bucket no tiene datos
+type timeseriesLatencyPercentiles struct {
+	P50 *float64 `json:"p50"`
+	P95 *float64 `json:"p95"`
+	P99 *float64 `json:"p99"`
+}
+
+// GetLogsTimeseries agrupa los logs en intervalos (interval=minute, hour o day; hour por defecto)
+// con el número de peticiones, de errores y los percentiles de responseTime de cada uno.
+// Acepta los filtros de logFilterParams; sin from se usa un rango por defecto según el intervalo.
+// Los intervalos sin logs se devuelven con ceros para poder graficarlos.
+// Requiere MongoDB 7.0 o superior ($percentile).
+func (h *Handler) GetLogsTimeseries(c *fiber.Ctx) error {
+	intervalName := strings.ToLower(c.Query("interval", "hour"))
+	interval, ok := timeseriesIntervals[intervalName]
+	if !ok {
+		h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "interval debe ser minute, hour o day"})
+	}
+
+	filter, err := logFilterParams(c)
+	if err != nil {
+		h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// El rango siempre queda acotado para limitar el número de buckets
+	from, to, _ := logTimeRange(c) // ya validado por logFilterParams
+	if to == nil {
+		now := time.Now()
+		to = &now
+	}
+	if from == nil {
+		start := to.Add(-interval.defaultWindow)
+		from = &start
+	}
+	if from.After(*to) {
+		h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "from debe ser anterior a to"})
+	}
+	first := from.UTC().Truncate(interval.step)
+	last := to.UTC().Truncate(interval.step)
+	bucketCount := int(last.Sub(first)/interval.step) + 1
+	if bucketCount > maxTimeseriesBuckets {
+		h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("El rango genera %d intervalos (máximo %d); acorta el rango o usa un intervalo mayor", bucketCount, maxTimeseriesBuckets),
+		})
+	}
+	filter["timestamp"] = bson.M{"$gte": *from, "$lte": *to}
+
+	pipeline := bson.A{
+		bson.M{"$match": filter},
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$dateTrunc": bson.M{"date": "$timestamp", "unit": intervalName}},
+			"count": bson.M{"$sum": 1},
+			// Un status no numérico no es un error (en BSON un string es mayor que cualquier número).
+			// También cuenta logLevel "error": los logs anteriores a ActionLogger.Middleware
+			// se guardaron siempre con status 200.
+			"errors": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$or": bson.A{
+					bson.M{"$and": bson.A{bson.M{"$isNumber": "$status"}, bson.M{"$gte": bson.A{"$status", 400}}}},
+					bson.M{"$eq": bson.A{"$logLevel", "error"}},
+				}},
+				1,
+				0,
+			}}},
+			"responseTime": bson.M{"$percentile": bson.M{
+				"input":  "$responseTime",
+				"p":      bson.A{0.5, 0.95, 0.99},
+				"method": "approximate",
+			}},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := h.db.Collection("logs").Aggregate(ctx, pipeline)
+	if err != nil {
+		h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener la serie temporal de logs"})
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Start        time.Time  `bson:"_id"`
+		Count        int        `bson:"count"`
+		Errors       int        `bson:"errors"`
+		ResponseTime []*float64 `bson:"responseTime"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al procesar los logs"})
+	}
+
+	// Todos los buckets del rango, con ceros donde no hubo logs
+	buckets := make([]timeseriesBucket, bucketCount)
+	for i := range buckets {
+		buckets[i].Timestamp = first.Add(time.Duration(i) * interval.step)
+	}
+	for _, row := range rows {
+		i := int(row.Start.UTC().Sub(first) / interval.step)
+		if i < 0 || i >= bucketCount {
+			continue
+		}
+		bucket := &buckets[i]
+		bucket.Count = row.Count
+		bucket.Errors = row.Errors
+		if row.Count > 0 {
+			bucket.ErrorRate = float64(row.Errors) / float64(row.Count)
+		}
+		if len(row.ResponseTime) == 3 {
+			bucket.ResponseTime = timeseriesLatencyPercentiles{
+				P50: row.ResponseTime[0],
+				P95: row.ResponseTime[1],
+				P99: row.ResponseTime[2],
+			}
+		}
+	}
+
+	h.actions.LogAction(currentUserEmail(c), "getLogsTimeseries", "info")(c)
+	return c.Status(200).JSON(fiber.Map{
+		"interval": intervalName,
+		"from":     *from,
+		"to":       *to,
+		"buckets":  buckets,
+	})
+}
@@ -0,0 +1,352 @@
+// ./controllers/mfa_controller.go
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Vigencia del reto MFA emitido por Login tras validar la contraseña
+const mfaChallengeTTL = 5 * time.Minute
+
+// Tamaño en píxeles del código QR de enrolamiento
+const totpQRSize = 256
+
+// Cantidad de códigos de recuperación que se entregan al confirmar MFA
+const recoveryCodeCount = 10
+
+// Alfabeto de los códigos de recuperación (sin caracteres ambiguos como 0/O o 1/I)
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// StartMFAEnrollment genera un nuevo secreto TOTP pendiente de confirmación
+func (h *Handler) StartMFAEnrollment(c *fiber.Ctx) error {
+	email := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "mfaEnroll-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "mfaEnroll-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al iniciar el enrolamiento MFA"})
+	}
+
+	if user.MFAEnabled {
+		h.actions.LogAction(email, "mfaEnroll-error", "error")(c)
+		return c.Status(409).JSON(fiber.Map{"error": "MFA ya está habilitado"})
+	}
+
+	key, err := generateTOTPKey(email)
+	if err != nil {
+		h.actions.LogAction(email, "mfaEnroll-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al iniciar el enrolamiento MFA"})
+	}
+
+	qrCode, err := totpQRCode(key)
+	if err != nil {
+		h.actions.LogAction(email, "mfaEnroll-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al iniciar el enrolamiento MFA"})
+	}
+
+	if err := h.users.SetPendingMFASecret(ctx, email, key.Secret()); err != nil {
+		h.actions.LogAction(email, "mfaEnroll-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al iniciar el enrolamiento MFA"})
+	}
+
+	h.actions.LogAction(email, "mfaEnroll", "info")(c)
+	return c.JSON(fiber.Map{
+		"mfa_secret": key.URL(),
+		"qrCode":     qrCode,
+	})
+}
+
+// ConfirmMFAEnrollment habilita MFA si el código corresponde al secreto pendiente
+func (h *Handler) ConfirmMFAEnrollment(c *fiber.Ctx) error {
+	type ConfirmRequest struct {
+		Code string `json:"code"`
+	}
+
+	email := currentUserEmail(c)
+
+	var req ConfirmRequest
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al confirmar MFA"})
+	}
+
+	if user.MFAPendingSecret == "" {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "No hay un enrolamiento MFA pendiente"})
+	}
+
+	step, ok := matchTOTPStep(user.MFAPendingSecret, req.Code, time.Now())
+	if !ok {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Código OTP inválido o expirado"})
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al confirmar MFA"})
+	}
+
+	// Se guarda el paso del código de confirmación para que no pueda reutilizarse al iniciar sesión
+	if err := h.users.EnableMFA(ctx, email, user.MFAPendingSecret, hashes, step); err != nil {
+		h.actions.LogAction(email, "mfaConfirm-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al confirmar MFA"})
+	}
+
+	// Los códigos en claro solo se muestran esta vez
+	h.actions.LogAction(email, "mfaConfirm", "info")(c)
+	return c.JSON(fiber.Map{
+		"message":       "MFA habilitado",
+		"recoveryCodes": recoveryCodes,
+	})
+}
+
+// DisableMFA deshabilita MFA; exige volver a autenticarse con contraseña y código OTP
+func (h *Handler) DisableMFA(c *fiber.Ctx) error {
+	type DisableRequest struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	email := currentUserEmail(c)
+
+	var req DisableRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" || req.Code == "" {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al deshabilitar MFA"})
+	}
+
+	if !user.MFAEnabled || user.MFASecret == "" {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "El usuario no tiene 2FA habilitado"})
+	}
+
+	// Comparte el contador y el bloqueo de /verify-otp
+	if lockedUntil := otpLockedUntil(user); time.Now().Before(lockedUntil) {
+		return h.otpLockedResponse(c, email, lockedUntil)
+	}
+
+	if lockedUntil := loginLockedUntil(user); time.Now().Before(lockedUntil) {
+		return h.loginLockedResponse(c, email, lockedUntil)
+	}
+
+	validPassword, err := h.checkPassword(c, ctx, user, req.Password)
+	if err != nil {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al deshabilitar MFA"})
+	}
+	if !validPassword {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Credenciales incorrectas"})
+	}
+
+	isValid, err := h.verifyTOTPCode(ctx, email, user.MFASecret, req.Code)
+	if err != nil {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al deshabilitar MFA"})
+	}
+	if !isValid {
+		if err := h.handleOTPFailure(c, ctx, email); err != nil {
+			h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al deshabilitar MFA"})
+		}
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Código OTP inválido o expirado"})
+	}
+
+	if err := h.resetOTPFailures(ctx, email); err != nil {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al deshabilitar MFA"})
+	}
+
+	if err := h.users.DisableMFA(ctx, email); err != nil {
+		h.actions.LogAction(email, "mfaDisable-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al deshabilitar MFA"})
+	}
+
+	h.actions.LogAction(email, "mfaDisable", "info")(c)
+	return c.JSON(fiber.Map{"message": "MFA deshabilitado"})
+}
+
+// GetRecoveryCodesStatus devuelve cuántos códigos de recuperación le quedan al usuario
+func (h *Handler) GetRecoveryCodesStatus(c *fiber.Ctx) error {
+	email := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "getRecoveryCodes-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "getRecoveryCodes-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener los códigos de recuperación"})
+	}
+
+	h.actions.LogAction(email, "getRecoveryCodes", "info")(c)
+	return c.JSON(fiber.Map{"remaining": user.RemainingRecoveryCodes()})
+}
+
+// RegenerateRecoveryCodes reemplaza todos los códigos de recuperación por un juego nuevo
+func (h *Handler) RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	email := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "regenerateRecoveryCodes-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "regenerateRecoveryCodes-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al generar los códigos de recuperación"})
+	}
+
+	if !user.MFAEnabled {
+		h.actions.LogAction(email, "regenerateRecoveryCodes-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "El usuario no tiene 2FA habilitado"})
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		h.actions.LogAction(email, "regenerateRecoveryCodes-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al generar los códigos de recuperación"})
+	}
+
+	if err := h.users.SetRecoveryCodes(ctx, email, hashes); err != nil {
+		h.actions.LogAction(email, "regenerateRecoveryCodes-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al generar los códigos de recuperación"})
+	}
+
+	h.actions.LogAction(email, "regenerateRecoveryCodes", "info")(c)
+	return c.JSON(fiber.Map{"recoveryCodes": recoveryCodes})
+}
+
+// generateRecoveryCodes crea los códigos de recuperación en claro y sus hashes bcrypt
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		for j, b := range raw {
+			raw[j] = recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)]
+		}
+		code := string(raw)
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
+
+// consumeRecoveryCode valida un código de recuperación y lo elimina para que no pueda reutilizarse
+func (h *Handler) consumeRecoveryCode(ctx context.Context, user *models.User, code string) (bool, error) {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	for _, hash := range user.MFARecoveryCodes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(normalized)) != nil {
+			continue
+		}
+		// Si otra petición ya lo usó, el repositorio no lo elimina y se rechaza
+		return h.users.ConsumeRecoveryCode(ctx, user.Email, hash)
+	}
+	return false, nil
+}
+
+// generateTOTPKey crea un nuevo secreto TOTP para la cuenta
+func generateTOTPKey(email string) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      "Actividadr-Back",
+		AccountName: email,
+	})
+}
+
+// totpQRCode renderiza el QR del secreto como PNG codificado en data URI
+func totpQRCode(key *otp.Key) (string, error) {
+	img, err := key.Image(totpQRSize, totpQRSize)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// issueMFAChallenge crea un reto MFA de un solo uso ligado al usuario que pasó
+// la verificación de contraseña; solo se guarda su hash
+func (h *Handler) issueMFAChallenge(ctx context.Context, email string) (string, error) {
+	challenge, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if err := h.tokens.MFAChallenges.Create(ctx, utils.HashToken(challenge), email, now, now.Add(mfaChallengeTTL)); err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// consumeMFAChallenge elimina un reto MFA vigente y devuelve su email. Es atómico:
+// si otra petición ya lo consumió o venció, devuelve repositories.ErrTokenNotFound.
+func (h *Handler) consumeMFAChallenge(ctx context.Context, challenge string) (string, error) {
+	return h.tokens.MFAChallenges.Consume(ctx, utils.HashToken(challenge), time.Now())
+}
@@ -0,0 +1,130 @@
+// ./controllers/otp_protection.go
+
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Parámetros TOTP (los mismos que usa totp.Validate)
+const (
+	totpPeriod = 30
+	totpSkew   = 1
+)
+
+// Política de bloqueo por códigos OTP fallidos: a partir de otpMaxFailedAttempts
+// cada fallo duplica el bloqueo, empezando en otpLockoutBase y sin pasar de otpLockoutMax
+const (
+	otpMaxFailedAttempts = 5
+	otpLockoutBase       = 30 * time.Second
+	otpLockoutMax        = time.Hour
+)
+
+// matchTOTPStep busca el paso de tiempo (ventana de 30 s) en el que el código es válido
+func matchTOTPStep(secret string, code string, now time.Time) (int64, bool) {
+	for offset := -totpSkew; offset <= totpSkew; offset++ {
+		t := now.Add(time.Duration(offset*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, t, totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return t.Unix() / totpPeriod, true
+		}
+	}
+	return 0, false
+}
+
+// verifyTOTPCode valida el código y registra su paso de tiempo de forma atómica,
+// de modo que un código ya aceptado (o uno anterior) no pueda volver a usarse
+func (h *Handler) verifyTOTPCode(ctx context.Context, email string, secret string, code string) (bool, error) {
+	step, ok := matchTOTPStep(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.recordTOTPStep(ctx, email, step)
+}
+
+// recordTOTPStep guarda el último paso aceptado solo si es posterior al anterior
+func (h *Handler) recordTOTPStep(ctx context.Context, email string, step int64) (bool, error) {
+	return h.users.AdvanceTOTPStep(ctx, email, step)
+}
+
+// otpLockedUntil devuelve hasta cuándo está bloqueada la verificación OTP del usuario
+func otpLockedUntil(user *models.User) time.Time {
+	if user.OTPLockedUntil != nil {
+		return *user.OTPLockedUntil
+	}
+	return time.Time{}
+}
+
+// recordOTPFailure incrementa el contador de fallos y, si se supera el umbral,
+// bloquea la verificación con espera exponencial. Devuelve el fin del bloqueo (cero si no hay).
+func (h *Handler) recordOTPFailure(ctx context.Context, email string) (time.Time, error) {
+	attempts, err := h.users.IncrementOTPFailures(ctx, email)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if attempts < otpMaxFailedAttempts {
+		return time.Time{}, nil
+	}
+
+	lockedUntil := time.Now().Add(otpLockoutDuration(attempts))
+	if err := h.users.SetOTPLockedUntil(ctx, email, lockedUntil); err != nil {
+		return time.Time{}, err
+	}
+	return lockedUntil, nil
+}
+
+// handleOTPFailure registra un código fallido y deja constancia en "logs" si provocó un bloqueo
+func (h *Handler) handleOTPFailure(c *fiber.Ctx, ctx context.Context, email string) error {
+	lockedUntil, err := h.recordOTPFailure(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !lockedUntil.IsZero() {
+		h.actions.LogAction(email, "verifyOtp-lockout", "warn")(c)
+	}
+	return nil
+}
+
+// otpLockedResponse responde a un intento mientras la verificación OTP está bloqueada
+func (h *Handler) otpLockedResponse(c *fiber.Ctx, email string, lockedUntil time.Time) error {
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	h.actions.LogAction(email, "verifyOtp-locked", "error")(c)
+	return c.Status(429).JSON(fiber.Map{
+		"success":    false,
+		"message":    "Demasiados intentos fallidos, intenta más tarde",
+		"retryAfter": retryAfter,
+	})
+}
+
+// otpLockoutDuration calcula el bloqueo para el número de intentos fallidos acumulados
+func otpLockoutDuration(attempts int) time.Duration {
+	exponent := float64(attempts - otpMaxFailedAttempts)
+	lockout := time.Duration(float64(otpLockoutBase) * math.Pow(2, exponent))
+	if lockout <= 0 || lockout > otpLockoutMax {
+		return otpLockoutMax
+	}
+	return lockout
+}
+
+// resetOTPFailures limpia el contador tras una verificación correcta
+func (h *Handler) resetOTPFailures(ctx context.Context, email string) error {
+	return h.users.ResetOTPFailures(ctx, email)
+}
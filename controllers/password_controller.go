@@ -0,0 +1,177 @@
+// ./controllers/password_controller.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/password"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Vigencia del token de restablecimiento de contraseña
+const passwordResetTTL = time.Hour
+
+// ForgotPassword envía un enlace de restablecimiento si el email está registrado.
+// La respuesta es la misma exista o no la cuenta, para no revelar qué emails están registrados.
+func (h *Handler) ForgotPassword(c *fiber.Ctx) error {
+	type ForgotRequest struct {
+		Email string `json:"email"`
+	}
+
+	var req ForgotRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		h.actions.LogAction("anonymous", "passwordForgot-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, req.Email)
+	if err != nil && err != repositories.ErrNotFound {
+		h.actions.LogAction("anonymous", "passwordForgot-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al solicitar el restablecimiento"})
+	}
+
+	if err == nil {
+		// El envío va a la cola de segundo plano y sus errores solo quedan en el log del
+		// servidor: así la respuesta y su duración no revelan si la cuenta existe
+		email := user.Email
+		queued := h.sends.Enqueue("restablecimiento de contraseña de "+email, func(ctx context.Context) error {
+			return h.sendPasswordReset(ctx, email)
+		})
+		if !queued {
+			log.Printf("Cola de envíos llena: se descarta el correo de restablecimiento a %s", email)
+		}
+		h.actions.LogAction(email, "passwordForgot", "info")(c)
+	} else {
+		h.actions.LogAction("anonymous", "passwordForgot", "info")(c)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Si el email está registrado, recibirás un enlace para restablecer tu contraseña",
+	})
+}
+
+// ResetPassword cambia la contraseña usando un token de restablecimiento de un solo uso
+// y cierra todas las sesiones existentes del usuario
+func (h *Handler) ResetPassword(c *fiber.Ctx) error {
+	type ResetRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	var req ResetRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" || req.Password == "" {
+		h.actions.LogAction("anonymous", "passwordReset-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tokenHash := utils.HashToken(req.Token)
+	now := time.Now()
+
+	email, err := h.tokens.PasswordResets.FindValid(ctx, tokenHash, now)
+	if err == repositories.ErrTokenNotFound {
+		h.actions.LogAction("anonymous", "passwordReset-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Token de restablecimiento inválido o expirado"})
+	} else if err != nil {
+		h.actions.LogAction("anonymous", "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Token de restablecimiento inválido o expirado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+
+	// Se valida antes de consumir el token para que el usuario pueda reintentar
+	if violations := h.passwords.Validate(req.Password, user.Username, email); len(violations) > 0 {
+		return h.rejectWeakPassword(c, email, "passwordReset", violations)
+	}
+
+	// Marcar el token como usado de forma atómica para que solo sirva una vez
+	marked, err := h.tokens.PasswordResets.MarkUsed(ctx, tokenHash, now)
+	if err != nil {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+	if !marked {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Token de restablecimiento inválido o expirado"})
+	}
+
+	hashedPassword, err := h.passwords.Hash.Hash(req.Password)
+	if err != nil {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+
+	err = h.users.SetPassword(ctx, email, hashedPassword, now)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Token de restablecimiento inválido o expirado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+
+	// Invalidar el resto de enlaces pendientes y todas las sesiones abiertas
+	if err := h.tokens.PasswordResets.InvalidateAll(ctx, email, now); err != nil {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+
+	if err := h.revokeUserSessions(ctx, email); err != nil {
+		h.actions.LogAction(email, "passwordReset-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al restablecer la contraseña"})
+	}
+
+	h.actions.LogAction(email, "passwordReset", "info")(c)
+	return c.JSON(fiber.Map{"message": "Contraseña restablecida con éxito"})
+}
+
+// rejectWeakPassword responde con las reglas de la política que la contraseña incumple
+func (h *Handler) rejectWeakPassword(c *fiber.Ctx, email string, action string, violations []password.Violation) error {
+	h.actions.LogAction(email, action+"-error", "error")(c)
+	return c.Status(400).JSON(fiber.Map{
+		"error":      "La contraseña no cumple la política",
+		"violations": violations,
+	})
+}
+
+// sendPasswordReset crea un token de restablecimiento (solo se guarda su hash) y lo envía por correo
+func (h *Handler) sendPasswordReset(ctx context.Context, email string) error {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := h.tokens.PasswordResets.Create(ctx, utils.HashToken(token), email, now, now.Add(passwordResetTTL)); err != nil {
+		return err
+	}
+
+	link := h.cfg.Auth.PasswordResetURL + "?token=" + url.QueryEscape(token)
+
+	body := fmt.Sprintf(
+		"Recibimos una solicitud para restablecer tu contraseña.\n\n"+
+			"Usa el siguiente enlace (válido por %d minutos):\n%s\n\n"+
+			"Si no fuiste tú, ignora este correo.",
+		int(passwordResetTTL.Minutes()), link,
+	)
+	return h.mailer.Send(email, "Restablecer contraseña", body)
+}
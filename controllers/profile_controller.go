@@ -0,0 +1,237 @@
+// ./controllers/profile_controller.go
+
+package controllers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetMe devuelve el perfil del usuario autenticado
+func (h *Handler) GetMe(c *fiber.Ctx) error {
+	email := currentUserEmail(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// models.User no serializa la contraseña ni los secretos MFA
+	profile, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "getMe-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "getMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al obtener el perfil"})
+	}
+
+	h.actions.LogAction(email, "getMe", "info")(c)
+	return c.JSON(profile)
+}
+
+// UpdateMe cambia el nombre de usuario y/o la contraseña del usuario autenticado.
+// Cambiar la contraseña exige la actual y cierra las demás sesiones.
+func (h *Handler) UpdateMe(c *fiber.Ctx) error {
+	type UpdateRequest struct {
+		Username        *string `json:"username"`
+		CurrentPassword string  `json:"currentPassword"`
+		NewPassword     string  `json:"newPassword"`
+	}
+
+	email := currentUserEmail(c)
+
+	var req UpdateRequest
+	if err := c.BodyParser(&req); err != nil || (req.Username == nil && req.NewPassword == "") {
+		h.actions.LogAction(email, "updateMe-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "updateMe-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "updateMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+	}
+
+	username := user.Username
+	usernameChanged := false
+
+	if req.Username != nil {
+		newUsername := strings.TrimSpace(*req.Username)
+		if newUsername == "" {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(400).JSON(fiber.Map{"error": "El nombre de usuario no puede estar vacío"})
+		}
+		if strings.Contains(newUsername, "@") {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(400).JSON(fiber.Map{"error": usernameWithAtError})
+		}
+
+		if newUsername != username {
+			taken, err := h.users.UsernameTaken(ctx, newUsername, email)
+			if err != nil {
+				h.actions.LogAction(email, "updateMe-error", "error")(c)
+				return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+			}
+			if taken {
+				h.actions.LogAction(email, "updateMe-error", "error")(c)
+				return c.Status(409).JSON(fiber.Map{"error": "El nombre de usuario ya está en uso"})
+			}
+			username = newUsername
+			usernameChanged = true
+		}
+	}
+
+	passwordChanged := req.NewPassword != ""
+	var hashedPassword string
+	if passwordChanged {
+		if req.CurrentPassword == "" {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(401).JSON(fiber.Map{"error": "La contraseña actual es incorrecta"})
+		}
+		if lockedUntil := loginLockedUntil(user); time.Now().Before(lockedUntil) {
+			return h.loginLockedResponse(c, email, lockedUntil)
+		}
+
+		validPassword, err := h.checkPassword(c, ctx, user, req.CurrentPassword)
+		if err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+		if !validPassword {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(401).JSON(fiber.Map{"error": "La contraseña actual es incorrecta"})
+		}
+
+		if violations := h.passwords.Validate(req.NewPassword, username, email); len(violations) > 0 {
+			return h.rejectWeakPassword(c, email, "updateMe", violations)
+		}
+
+		hashedPassword, err = h.passwords.Hash.Hash(req.NewPassword)
+		if err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+	}
+
+	// Todo se valida antes de escribir para no dejar el perfil a medio actualizar
+	if usernameChanged {
+		// Otra cuenta pudo tomar el nombre después de la comprobación
+		err := h.users.SetUsername(ctx, email, username)
+		if err == repositories.ErrDuplicate {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(409).JSON(fiber.Map{"error": "El nombre de usuario ya está en uso"})
+		} else if err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+	}
+	if passwordChanged {
+		if err := h.users.SetPassword(ctx, email, hashedPassword, time.Now()); err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+	}
+
+	profile, err := h.users.FindByEmail(ctx, email)
+	if err != nil {
+		h.actions.LogAction(email, "updateMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+	}
+	response := fiber.Map{"user": profile}
+
+	// Con la contraseña nueva se cierran todas las sesiones y se entrega un par de tokens nuevo
+	if passwordChanged {
+		if err := h.revokeUserSessions(ctx, email); err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+
+		token, err := h.generateJWT(email, userRole(user))
+		if err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+		refreshToken, err := h.issueRefreshToken(ctx, email, "")
+		if err != nil {
+			h.actions.LogAction(email, "updateMe-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error al actualizar el perfil"})
+		}
+		response["token"] = token
+		response["refreshToken"] = refreshToken
+	}
+
+	h.actions.LogAction(email, "updateMe", "info")(c)
+	return c.JSON(response)
+}
+
+// DeleteMe elimina la cuenta del usuario autenticado tras confirmar su contraseña
+func (h *Handler) DeleteMe(c *fiber.Ctx) error {
+	type DeleteRequest struct {
+		Password string `json:"password"`
+	}
+
+	email := currentUserEmail(c)
+
+	var req DeleteRequest
+	if err := c.BodyParser(&req); err != nil || req.Password == "" {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Debes confirmar tu contraseña"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al eliminar la cuenta"})
+	}
+
+	if lockedUntil := loginLockedUntil(user); time.Now().Before(lockedUntil) {
+		return h.loginLockedResponse(c, email, lockedUntil)
+	}
+
+	validPassword, err := h.checkPassword(c, ctx, user, req.Password)
+	if err != nil {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al eliminar la cuenta"})
+	}
+	if !validPassword {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Credenciales incorrectas"})
+	}
+
+	if err := h.revokeUserSessions(ctx, email); err != nil {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al eliminar la cuenta"})
+	}
+
+	if err := h.users.Delete(ctx, email); err != nil && err != repositories.ErrNotFound {
+		h.actions.LogAction(email, "deleteMe-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al eliminar la cuenta"})
+	}
+
+	// Los datos auxiliares expiran solos, pero se eliminan para no dejar rastro de la cuenta
+	if err := h.tokens.MFAChallenges.DeleteByEmail(ctx, email); err != nil {
+		log.Printf("Error al limpiar los retos MFA de %s: %v", email, err)
+	}
+	if err := h.tokens.PasswordResets.DeleteByEmail(ctx, email); err != nil {
+		log.Printf("Error al limpiar los restablecimientos de contraseña de %s: %v", email, err)
+	}
+
+	h.actions.LogAction(email, "deleteMe", "info")(c)
+	return c.JSON(fiber.Map{"message": "Cuenta eliminada"})
+}
@@ -0,0 +1,217 @@
+// ./controllers/token_controller.go
+
+package controllers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Vida de un refresh token; cada rotación emite uno nuevo con la vigencia completa
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// RefreshToken canjea un refresh token por un nuevo par de tokens (rotación).
+// Si se presenta un refresh token ya rotado se asume robo y se revoca toda su familia.
+func (h *Handler) RefreshToken(c *fiber.Ctx) error {
+	type RefreshRequest struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	var req RefreshRequest
+	if err := c.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		h.actions.LogAction("anonymous", "refreshToken-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tokenHash := utils.HashToken(req.RefreshToken)
+
+	// Marcar el token como usado de forma atómica para que solo una petición pueda canjearlo
+	current, err := h.tokens.RefreshTokens.Redeem(ctx, tokenHash, time.Now())
+	if err == repositories.ErrTokenNotFound {
+		return h.rejectRefreshToken(c, ctx, tokenHash)
+	} else if err != nil {
+		h.actions.LogAction("anonymous", "refreshToken-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al renovar la sesión"})
+	}
+
+	email := current.Email
+	familyID := current.FamilyID
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		h.revokeRefreshFamily(ctx, familyID)
+		h.actions.LogAction(email, "refreshToken-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Refresh token inválido"})
+	} else if err != nil {
+		h.actions.LogAction(email, "refreshToken-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al renovar la sesión"})
+	}
+
+	if denied := accountAccessDenied(user); denied != nil {
+		h.revokeRefreshFamily(ctx, familyID)
+		h.actions.LogAction(email, "refreshToken-denied", "error")(c)
+		return c.Status(403).JSON(denied)
+	}
+
+	token, err := h.generateJWT(email, userRole(user))
+	if err != nil {
+		h.actions.LogAction(email, "refreshToken-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al renovar la sesión"})
+	}
+
+	refreshToken, err := h.issueRefreshToken(ctx, email, familyID)
+	if err != nil {
+		h.actions.LogAction(email, "refreshToken-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al renovar la sesión"})
+	}
+
+	h.actions.LogAction(email, "refreshToken", "info")(c)
+	return c.JSON(fiber.Map{
+		"token":        token,
+		"refreshToken": refreshToken,
+	})
+}
+
+// Logout revoca el access token actual y, si se envía, la familia de su refresh token
+func (h *Handler) Logout(c *fiber.Ctx) error {
+	type LogoutRequest struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	// El cuerpo es opcional
+	var req LogoutRequest
+	_ = c.BodyParser(&req)
+
+	claims := c.Locals("user").(jwt.MapClaims)
+	email, _ := claims["email"].(string)
+	jti, _ := claims["jti"].(string)
+
+	expiresAt := time.Now().Add(accessTokenTTL)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		expiresAt = exp.Time
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.revocations.RevokeToken(ctx, jti, email, expiresAt); err != nil {
+		h.actions.LogAction(email, "logout-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al cerrar sesión"})
+	}
+
+	if req.RefreshToken != "" {
+		// Solo se revoca la familia si el refresh token pertenece al usuario del access token
+		stored, err := h.tokens.RefreshTokens.FindByHash(ctx, utils.HashToken(req.RefreshToken))
+		if err == nil && stored.Email == email {
+			h.revokeRefreshFamily(ctx, stored.FamilyID)
+		}
+	}
+
+	h.actions.LogAction(email, "logout", "info")(c)
+	return c.JSON(fiber.Map{"message": "Sesión cerrada"})
+}
+
+// LogoutAll revoca todos los tokens del usuario en todos sus dispositivos
+func (h *Handler) LogoutAll(c *fiber.Ctx) error {
+	claims := c.Locals("user").(jwt.MapClaims)
+	email, _ := claims["email"].(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.revokeUserSessions(ctx, email); err != nil {
+		h.actions.LogAction(email, "logoutAll-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al cerrar sesión"})
+	}
+
+	h.actions.LogAction(email, "logoutAll", "info")(c)
+	return c.JSON(fiber.Map{"message": "Se cerraron todas las sesiones"})
+}
+
+// revokeUserSessions invalida todos los access tokens y refresh tokens de un usuario
+func (h *Handler) revokeUserSessions(ctx context.Context, email string) error {
+	if err := h.revocations.RevokeAllTokens(ctx, email, accessTokenTTL); err != nil {
+		return err
+	}
+	return h.tokens.RefreshTokens.RevokeAll(ctx, email, time.Now())
+}
+
+// rejectRefreshToken responde a un refresh token que no se pudo canjear y
+// detecta la reutilización de tokens ya rotados
+func (h *Handler) rejectRefreshToken(c *fiber.Ctx, ctx context.Context, tokenHash string) error {
+	stored, err := h.tokens.RefreshTokens.FindByHash(ctx, tokenHash)
+	if err == repositories.ErrTokenNotFound {
+		h.actions.LogAction("anonymous", "refreshToken-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Refresh token inválido"})
+	} else if err != nil {
+		h.actions.LogAction("anonymous", "refreshToken-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al renovar la sesión"})
+	}
+
+	email := stored.Email
+
+	if stored.Used {
+		// Un token rotado volvió a presentarse: se invalida toda la familia
+		h.revokeRefreshFamily(ctx, stored.FamilyID)
+		h.actions.LogAction(email, "refreshToken-reuse", "warn")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Refresh token inválido"})
+	}
+
+	if stored.Revoked {
+		h.actions.LogAction(email, "refreshToken-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"error": "Refresh token inválido"})
+	}
+
+	h.actions.LogAction(email, "refreshToken-error", "error")(c)
+	return c.Status(401).JSON(fiber.Map{"error": "Refresh token expirado"})
+}
+
+// issueRefreshToken crea un refresh token para el usuario y guarda solo su hash.
+// Con familyID vacío se inicia una nueva familia (nuevo inicio de sesión).
+func (h *Handler) issueRefreshToken(ctx context.Context, email string, familyID string) (string, error) {
+	if familyID == "" {
+		id, err := utils.GenerateRandomToken(16)
+		if err != nil {
+			return "", err
+		}
+		familyID = id
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = h.tokens.RefreshTokens.Create(ctx, &models.RefreshToken{
+		TokenHash: utils.HashToken(token),
+		Email:     email,
+		FamilyID:  familyID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// revokeRefreshFamily revoca todos los refresh tokens de una familia
+func (h *Handler) revokeRefreshFamily(ctx context.Context, familyID string) {
+	if familyID == "" {
+		return
+	}
+	if err := h.tokens.RefreshTokens.RevokeFamily(ctx, familyID, time.Now()); err != nil {
+		log.Println("Error al revocar la familia de refresh tokens:", err)
+	}
+}
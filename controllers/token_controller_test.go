@@ -0,0 +1,58 @@
+// ./controllers/token_controller_test.go
+
+package controllers
+
+import "testing"
+
+func TestRefreshTokenRotation(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser(t, "ana@example.com", "ana", nil)
+
+	status, body := s.login(t, "ana", testPassword)
+	if status != 200 {
+		t.Fatalf("login: estado %d, respuesta %v", status, body)
+	}
+	first := stringField(t, body, "refreshToken")
+
+	status, body = s.post(t, "/refresh-token", map[string]string{"refreshToken": first})
+	if status != 200 {
+		t.Fatalf("renovar: estado %d, respuesta %v", status, body)
+	}
+	stringField(t, body, "token")
+	second := stringField(t, body, "refreshToken")
+	if second == first {
+		t.Fatal("la rotación devolvió el mismo refresh token")
+	}
+
+	status, body = s.post(t, "/refresh-token", map[string]string{"refreshToken": second})
+	if status != 200 {
+		t.Fatalf("renovar con el token rotado: estado %d, respuesta %v", status, body)
+	}
+	third := stringField(t, body, "refreshToken")
+
+	// Reutilizar un token ya rotado revoca toda la familia, incluido el último emitido
+	if status, body := s.post(t, "/refresh-token", map[string]string{"refreshToken": first}); status != 401 {
+		t.Fatalf("token reutilizado: estado %d, respuesta %v", status, body)
+	}
+	if status, body := s.post(t, "/refresh-token", map[string]string{"refreshToken": third}); status != 401 {
+		t.Fatalf("familia revocada: estado %d, respuesta %v", status, body)
+	}
+
+	// Otra sesión del mismo usuario no se ve afectada
+	_, body = s.login(t, "ana", testPassword)
+	other := stringField(t, body, "refreshToken")
+	if status, body := s.post(t, "/refresh-token", map[string]string{"refreshToken": other}); status != 200 {
+		t.Fatalf("otra sesión: estado %d, respuesta %v", status, body)
+	}
+}
+
+func TestRefreshTokenRejectsUnknownToken(t *testing.T) {
+	s := newTestServer(t)
+
+	if status, body := s.post(t, "/refresh-token", map[string]string{"refreshToken": "desconocido"}); status != 401 {
+		t.Errorf("estado %d, respuesta %v", status, body)
+	}
+	if status, body := s.post(t, "/refresh-token", map[string]string{}); status != 400 {
+		t.Errorf("sin token: estado %d, respuesta %v", status, body)
+	}
+}
@@ -4,26 +4,25 @@ package controllers
 
 import (
 	"context"
+	"log"
 	"math/rand"
-	"os"
 	"runtime"
 	"strings"
 	"time"
 
-	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/metrics"
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"github.com/Ana-Gabs/actividadr-back/repositories"
 	"github.com/Ana-Gabs/actividadr-back/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/pquerna/otp/totp"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"golang.org/x/crypto/bcrypt"
 )
 
-func GetInfo(c *fiber.Ctx) error {
+
+func (h *Handler) GetInfo(c *fiber.Ctx) error {
 
 	if rand.Float64() < 0.3 {
-		utils.LogAction("anonymous", "getInfo-error", "error")(c)
+		h.actions.LogAction("anonymous", "getInfo-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error interno del servidor"})
 	}
 
@@ -35,12 +34,16 @@ func GetInfo(c *fiber.Ctx) error {
 		},
 	}
 
-	utils.LogAction("anonymous", "getInfo", "info")(c)
+	h.actions.LogAction("anonymous", "getInfo", "info")(c)
 	return c.JSON(info)
 }
 
 
-func Register(c *fiber.Ctx) error {
+// Los nombres de usuario no pueden contener "@": el login decide por ella si el
+// identificador es un email o un nombre de usuario
+const usernameWithAtError = "El nombre de usuario no puede contener @"
+
+func (h *Handler) Register(c *fiber.Ctx) error {
 	type RegisterRequest struct {
 		Email    string `json:"email"`
 		Username string `json:"username"`
@@ -49,195 +52,419 @@ func Register(c *fiber.Ctx) error {
 
 	var req RegisterRequest
 	if err := c.BodyParser(&req); err != nil {
-		utils.LogAction("anonymous", "register-error", "error")(c)
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
 		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
 	}
 
 	if req.Email == "" || req.Username == "" || req.Password == "" {
-		utils.LogAction("anonymous", "register-error", "error")(c)
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
 		return c.Status(400).JSON(fiber.Map{"error": "Todos los campos son obligatorios"})
 	}
 
 	if !strings.Contains(req.Email, "@") || !strings.Contains(req.Email, ".") {
-		utils.LogAction("anonymous", "register-error", "error")(c)
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
 		return c.Status(400).JSON(fiber.Map{"error": "Email inválido"})
 	}
 
-	collection := config.GetCollection("users")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if strings.Contains(req.Username, "@") {
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": usernameWithAtError})
+	}
 
-	var existingUser bson.M
-	err := collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&existingUser)
-	if err != mongo.ErrNoDocuments {
-		utils.LogAction("anonymous", "register-error", "error")(c)
-		return c.Status(400).JSON(fiber.Map{"error": "El usuario ya existe"})
+	if violations := h.passwords.Validate(req.Password, req.Username, req.Email); len(violations) > 0 {
+		return h.rejectWeakPassword(c, "anonymous", "register", violations)
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	hashedPassword, err := h.passwords.Hash.Hash(req.Password)
 	if err != nil {
-		utils.LogAction("anonymous", "register-error", "error")(c)
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error en el registro"})
 	}
 
-	key, err := totp.Generate(totp.GenerateOpts{
-		Issuer:      "Actividadr-Back",
-		AccountName: req.Email,
-	})
+	// El secreto queda pendiente: MFA se habilita solo al confirmar un código en /mfa/confirm
+	key, err := generateTOTPKey(req.Email)
 	if err != nil {
-		utils.LogAction("anonymous", "register-error", "error")(c)
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error en el registro"})
 	}
 
-	_, err = collection.InsertOne(ctx, bson.M{
-		"email":         req.Email,
-		"username":      req.Username,
-		"password":      string(hashedPassword),
-		"mfa_secret":    key.Secret(),
-		"mfaEnabled":    true,
-		"date_register": time.Now(),
-		"last_login":    nil,
+	now := time.Now()
+	emailVerified := false
+	err = h.users.Create(ctx, &models.User{
+		Email:              req.Email,
+		Username:           req.Username,
+		Password:           hashedPassword,
+		MFAPendingSecret:   key.Secret(),
+		MFAEnabled:         false,
+		Role:               utils.RoleUser,
+		EmailVerified:      &emailVerified,
+		VerificationSentAt: &now,
+		DateRegister:       now,
 	})
-	if err != nil {
-		utils.LogAction("anonymous", "register-error", "error")(c)
+	// Los índices únicos (sin distinguir mayúsculas) resuelven también registros simultáneos
+	if err == repositories.ErrDuplicate {
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
+		return c.Status(409).JSON(fiber.Map{"error": "El email o el nombre de usuario ya están registrados"})
+	} else if err != nil {
+		h.actions.LogAction("anonymous", "register-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error en el registro"})
 	}
 
-	utils.LogAction(req.Email, "register", "info")(c)
-	return c.Status(201).JSON(fiber.Map{
-		"message":    "Usuario registrado con éxito",
-		"mfa_secret": key.URL(),
-	})
+	// La cuenta ya existe aunque falle el envío; el usuario puede pedir el reenvío
+	verificationSent := true
+	if err := h.sendVerificationEmail(req.Email); err != nil {
+		log.Println("Error al enviar el correo de verificación:", err)
+		verificationSent = false
+	}
+
+	response := fiber.Map{
+		"message":               "Usuario registrado con éxito. Revisa tu correo para verificar tu cuenta",
+		"mfa_secret":            key.URL(),
+		"mfaPending":            true,
+		"verificationEmailSent": verificationSent,
+	}
+
+	// Con ?qr=true se incluye el código QR en PNG (data URI)
+	if c.QueryBool("qr") {
+		qrCode, err := totpQRCode(key)
+		if err != nil {
+			h.actions.LogAction("anonymous", "register-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error en el registro"})
+		}
+		response["qrCode"] = qrCode
+	}
+
+	h.actions.LogAction(req.Email, "register", "info")(c)
+	return c.Status(201).JSON(response)
 }
 
-func Login(c *fiber.Ctx) error {
+func (h *Handler) Login(c *fiber.Ctx) error {
 	type LoginRequest struct {
 		EmailOrUsername string `json:"emailOrUsername"`
 		Password        string `json:"password"`
 	}
 
+	// Resultado para la métrica de logins; cada salida lo ajusta
+	outcome := metrics.LoginError
+	defer func() { h.metrics.LoginAttempt(outcome) }()
+
 	var req LoginRequest
 	if err := c.BodyParser(&req); err != nil {
-		utils.LogAction("anonymous", "login-error", "error")(c)
+		outcome = metrics.LoginBadRequest
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
 		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
 	}
 
-	collection := config.GetCollection("users")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var user bson.M
-	err := collection.FindOne(ctx, bson.M{
-		"$or": []bson.M{
-			{"email": req.EmailOrUsername},
-			{"username": req.EmailOrUsername},
-		},
-	}).Decode(&user)
-	if err == mongo.ErrNoDocuments {
-		utils.LogAction("anonymous", "login-error", "error")(c)
+	user, err := h.users.FindByEmailOrUsername(ctx, req.EmailOrUsername)
+	if err == repositories.ErrNotFound {
+		outcome = metrics.LoginFailure
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
 		return c.Status(401).JSON(fiber.Map{"error": "Credenciales incorrectas"})
 	} else if err != nil {
-		utils.LogAction("anonymous", "login-error", "error")(c)
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
 	}
 
-	
-	if err := bcrypt.CompareHashAndPassword([]byte(user["password"].(string)), []byte(req.Password)); err != nil {
-		utils.LogAction("anonymous", "login-error", "error")(c)
+	if lockedUntil := loginLockedUntil(user); time.Now().Before(lockedUntil) {
+		outcome = metrics.LoginLocked
+		return h.loginLockedResponse(c, user.Email, lockedUntil)
+	}
+
+	validPassword, needsRehash, err := h.passwords.Hash.Verify(req.Password, user.Password)
+	if err != nil {
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
+	}
+	if !validPassword {
+		locked, err := h.recordLoginFailure(ctx, user.Email)
+		if err != nil {
+			h.actions.LogAction("anonymous", "login-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
+		}
+		if locked {
+			h.actions.LogAction(user.Email, "login-lockout", "warn")(c)
+		}
+		outcome = metrics.LoginFailure
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
 		return c.Status(401).JSON(fiber.Map{"error": "Credenciales incorrectas"})
 	}
 
+	if err := h.resetLoginFailures(ctx, user.Email); err != nil {
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
+	}
+
+	// Migrar el hash a los parámetros actuales ahora que se conoce la contraseña
+	if needsRehash {
+		h.rehashPassword(ctx, user.Email, req.Password)
+	}
+
+	if denied := accountAccessDenied(user); denied != nil {
+		outcome = metrics.LoginDenied
+		h.actions.LogAction(user.Email, "login-denied", "error")(c)
+		return c.Status(403).JSON(denied)
+	}
+
 	
-	if user["mfaEnabled"].(bool) {
-		utils.LogAction(user["email"].(string), "login-mfa-required", "info")(c)
+	if user.MFAEnabled {
+		// El segundo paso solo acepta este reto, no un email arbitrario
+		challenge, err := h.issueMFAChallenge(ctx, user.Email)
+		if err != nil {
+			h.actions.LogAction("anonymous", "login-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
+		}
+
+		outcome = metrics.LoginMFARequired
+		h.actions.LogAction(user.Email, "login-mfa-required", "info")(c)
 		return c.JSON(fiber.Map{
 			"requiresMFA": true,
-			"email":       user["email"],
+			"mfaToken":    challenge,
 		})
 	}
 
 	
-	token, err := generateJWT(user["email"].(string))
+	token, err := h.generateJWT(user.Email, userRole(user))
 	if err != nil {
-		utils.LogAction("anonymous", "login-error", "error")(c)
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
 	}
 
 	
-	_, err = collection.UpdateOne(ctx, bson.M{"email": user["email"]}, bson.M{
-		"$set": bson.M{"last_login": time.Now()},
-	})
+	refreshToken, err := h.issueRefreshToken(ctx, user.Email, "")
 	if err != nil {
-		utils.LogAction("anonymous", "login-error", "error")(c)
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
 	}
 
-	utils.LogAction(user["email"].(string), "login", "info")(c)
-	return c.JSON(fiber.Map{"token": token})
+	if err := h.users.UpdateLastLogin(ctx, user.Email, time.Now()); err != nil {
+		h.actions.LogAction("anonymous", "login-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error en el login"})
+	}
+
+	outcome = metrics.LoginSuccess
+	h.actions.LogAction(user.Email, "login", "info")(c)
+	return c.JSON(fiber.Map{
+		"token":        token,
+		"refreshToken": refreshToken,
+	})
 }
 
 
-func VerifyOtp(c *fiber.Ctx) error {
+func (h *Handler) VerifyOtp(c *fiber.Ctx) error {
 	type OtpRequest struct {
-		Email string `json:"email"`
-		Token string `json:"token"`
+		MFAToken     string `json:"mfaToken"`
+		Token        string `json:"token"`
+		RecoveryCode string `json:"recoveryCode"`
 	}
 
+	// Resultado para la métrica de verificaciones OTP; cada salida lo ajusta
+	outcome := metrics.OTPError
+	defer func() { h.metrics.OTPVerification(outcome) }()
+
 	var req OtpRequest
-	if err := c.BodyParser(&req); err != nil {
-		utils.LogAction("anonymous", "verifyOtp-error", "error")(c)
+	if err := c.BodyParser(&req); err != nil || req.MFAToken == "" || (req.Token == "" && req.RecoveryCode == "") {
+		outcome = metrics.OTPBadRequest
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
 		return c.Status(400).JSON(fiber.Map{"message": "Faltan datos en la solicitud"})
 	}
 
-	
-	collection := config.GetCollection("users")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	var user bson.M
-	err := collection.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
-	if err == mongo.ErrNoDocuments {
-		utils.LogAction("anonymous", "verifyOtp-error", "error")(c)
+	// El reto identifica al usuario que ya superó la verificación de contraseña. Es de un
+	// solo uso y se consume antes de gastar el código: así un reto ya usado o vencido no
+	// consume un código de recuperación. Si el código falla, hay que volver a hacer login.
+	email, err := h.consumeMFAChallenge(ctx, req.MFAToken)
+	if err == repositories.ErrTokenNotFound {
+		outcome = metrics.OTPInvalidChallenge
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
+		return c.Status(401).JSON(fiber.Map{"message": "Reto MFA inválido o expirado"})
+	} else if err != nil {
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
+	}
+
+	user, err := h.users.FindByEmail(ctx, email)
+	if err == repositories.ErrNotFound {
+		outcome = metrics.OTPInvalidChallenge
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
 		return c.Status(401).JSON(fiber.Map{"message": "Usuario no encontrado"})
 	} else if err != nil {
-		utils.LogAction("anonymous", "verifyOtp-error", "error")(c)
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
 	}
 
-	if user["mfa_secret"] == nil {
-		utils.LogAction("anonymous", "verifyOtp-error", "error")(c)
+	if denied := accountAccessDenied(user); denied != nil {
+		outcome = metrics.OTPDenied
+		h.actions.LogAction(email, "verifyOtp-denied", "error")(c)
+		return c.Status(403).JSON(denied)
+	}
+
+	if user.MFASecret == "" {
+		outcome = metrics.OTPBadRequest
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
 		return c.Status(400).JSON(fiber.Map{"message": "El usuario no tiene 2FA habilitado"})
 	}
 
-	
-	isValid := totp.Validate(req.Token, user["mfa_secret"].(string))
+	if lockedUntil := otpLockedUntil(user); time.Now().Before(lockedUntil) {
+		outcome = metrics.OTPLocked
+		return h.otpLockedResponse(c, email, lockedUntil)
+	}
+
+	// Se acepta un código TOTP o, como respaldo, un código de recuperación
+	usedRecoveryCode := req.Token == ""
+	var isValid bool
+	if usedRecoveryCode {
+		isValid, err = h.consumeRecoveryCode(ctx, user, req.RecoveryCode)
+	} else {
+		// Rechaza también códigos ya usados dentro de su ventana de validez
+		isValid, err = h.verifyTOTPCode(ctx, email, user.MFASecret, req.Token)
+	}
+	if err != nil {
+		h.actions.LogAction(email, "verifyOtp-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
+	}
+
 	if !isValid {
-		utils.LogAction(req.Email, "verifyOtp-error", "error")(c)
+		if err := h.handleOTPFailure(c, ctx, email); err != nil {
+			h.actions.LogAction(email, "verifyOtp-error", "error")(c)
+			return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
+		}
+
+		message := "Código OTP inválido o expirado"
+		if usedRecoveryCode {
+			message = "Código de recuperación inválido"
+		}
+		outcome = metrics.OTPInvalid
+		h.actions.LogAction(email, "verifyOtp-error", "error")(c)
 		return c.Status(401).JSON(fiber.Map{
 			"success": false,
-			"message": "Código OTP inválido o expirado",
+			"message": message,
 		})
 	}
 
+	if err := h.resetOTPFailures(ctx, email); err != nil {
+		h.actions.LogAction(email, "verifyOtp-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
+	}
+
 	
-	token, err := generateJWT(user["email"].(string))
+	token, err := h.generateJWT(user.Email, userRole(user))
 	if err != nil {
-		utils.LogAction("anonymous", "verifyOtp-error", "error")(c)
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
 		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
 	}
 
-	utils.LogAction(req.Email, "verifyOtp-success", "info")(c)
-	return c.JSON(fiber.Map{
-		"success": true,
-		"token":   token,
-	})
+	refreshToken, err := h.issueRefreshToken(ctx, user.Email, "")
+	if err != nil {
+		h.actions.LogAction("anonymous", "verifyOtp-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
+	}
+
+	if err := h.users.UpdateLastLogin(ctx, email, time.Now()); err != nil {
+		h.actions.LogAction(email, "verifyOtp-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"message": "Error interno del servidor"})
+	}
+
+	response := fiber.Map{
+		"success":      true,
+		"token":        token,
+		"refreshToken": refreshToken,
+	}
+
+	if usedRecoveryCode {
+		// El código ya se descontó en la base de datos
+		response["recoveryCodesRemaining"] = user.RemainingRecoveryCodes() - 1
+		outcome = metrics.OTPRecovery
+		h.actions.LogAction(email, "verifyOtp-recovery", "info")(c)
+		return c.JSON(response)
+	}
+
+	outcome = metrics.OTPSuccess
+	h.actions.LogAction(email, "verifyOtp-success", "info")(c)
+	return c.JSON(response)
 }
 
-func generateJWT(email string) (string, error) {
+// Vida de un access token JWT
+const accessTokenTTL = time.Hour
+
+func (h *Handler) generateJWT(email string, role string) (string, error) {
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"email": email,
-		"exp":   time.Now().Add(time.Hour).Unix(),
+		"role":  role,
+		"jti":   jti,
+		"iat":   now.Unix(),
+		"exp":   now.Add(accessTokenTTL).Unix(),
+		// Permite distinguir un token emitido justo después de una revocación global
+		utils.IssuedAtClaim: now.UnixMicro(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	return token.SignedString([]byte(h.cfg.Auth.JWTSecret))
+}
+
+// rehashPassword guarda la contraseña con el algoritmo y parámetros configurados.
+// Un fallo aquí no impide el login: se volverá a intentar en el siguiente.
+func (h *Handler) rehashPassword(ctx context.Context, email string, plain string) {
+	hashedPassword, err := h.passwords.Hash.Hash(plain)
+	if err != nil {
+		log.Println("Error al actualizar el hash de la contraseña:", err)
+		return
+	}
+	if err := h.users.UpdatePasswordHash(ctx, email, hashedPassword); err != nil {
+		log.Println("Error al actualizar el hash de la contraseña:", err)
+	}
+}
+
+// accountAccessDenied indica por qué la cuenta no puede iniciar sesión (nil si puede)
+func accountAccessDenied(user *models.User) fiber.Map {
+	if user.Disabled {
+		return fiber.Map{
+			"error": "La cuenta está deshabilitada",
+			"code":  "account_disabled",
+		}
+	}
+	if user.PasswordResetRequired {
+		return fiber.Map{
+			"error": "Debes restablecer tu contraseña antes de iniciar sesión",
+			"code":  "password_reset_required",
+		}
+	}
+	// Solo las cuentas creadas con verificación tienen email_verified; las anteriores se consideran verificadas
+	if !user.IsEmailVerified() {
+		return fiber.Map{
+			"error": "Debes verificar tu email antes de iniciar sesión",
+			"code":  "email_not_verified",
+		}
+	}
+	return nil
+}
+
+// userRole devuelve el rol del usuario; los documentos anteriores a los roles son "user"
+func userRole(user *models.User) string {
+	if utils.IsValidRole(user.Role) {
+		return user.Role
+	}
+	return utils.RoleUser
+}
+
+// currentUserEmail devuelve el email del JWT validado por AuthMiddleware
+func currentUserEmail(c *fiber.Ctx) string {
+	if claims, ok := c.Locals("user").(jwt.MapClaims); ok {
+		if email, ok := claims["email"].(string); ok && email != "" {
+			return email
+		}
+	}
+	return "anonymous"
 }
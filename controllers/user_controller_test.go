@@ -0,0 +1,185 @@
+// ./controllers/user_controller_test.go
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestLoginWithEmailOrUsername(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser(t, "ana@example.com", "ana", nil)
+
+	for _, identifier := range []string{"ana@example.com", "ANA@example.com", "ana", "Ana"} {
+		status, body := s.login(t, identifier, testPassword)
+		if status != 200 {
+			t.Fatalf("login con %q: estado %d, respuesta %v", identifier, status, body)
+		}
+		stringField(t, body, "token")
+		stringField(t, body, "refreshToken")
+	}
+
+	user, err := s.users.FindByEmail(context.Background(), "ana@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.LastLogin == nil {
+		t.Error("el login no guardó last_login")
+	}
+}
+
+func TestLoginRejectsInvalidCredentials(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser(t, "ana@example.com", "ana", nil)
+
+	if status, body := s.login(t, "ana@example.com", "Incorrecta-123"); status != 401 {
+		t.Errorf("contraseña incorrecta: estado %d, respuesta %v", status, body)
+	}
+	if status, body := s.login(t, "nadie@example.com", testPassword); status != 401 {
+		t.Errorf("usuario inexistente: estado %d, respuesta %v", status, body)
+	}
+}
+
+func TestLoginUsernameDoesNotMatchAnotherAccountEmail(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser(t, "ana@example.com", "ana", nil)
+	// Cuenta anterior a la validación de nombres: su nombre es el email de otra cuenta
+	s.createUser(t, "eva@example.com", "ANA@example.com", nil)
+
+	status, body := s.login(t, "ana@example.com", testPassword)
+	if status != 200 {
+		t.Fatalf("estado %d, respuesta %v", status, body)
+	}
+	stored, err := s.tokens.RefreshTokens.FindByHash(context.Background(), utils.HashToken(stringField(t, body, "refreshToken")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != "ana@example.com" {
+		t.Errorf("la sesión es de %s, se esperaba ana@example.com", stored.Email)
+	}
+}
+
+func TestLoginLocksAccountAfterRepeatedFailures(t *testing.T) {
+	s := newTestServer(t)
+	s.createUser(t, "ana@example.com", "ana", nil)
+
+	// Un acierto antes del límite reinicia el contador
+	s.login(t, "ana", "Incorrecta-123")
+	s.login(t, "ana", "Incorrecta-123")
+	if status, body := s.login(t, "ana", testPassword); status != 200 {
+		t.Fatalf("login antes del bloqueo: estado %d, respuesta %v", status, body)
+	}
+
+	for i := 1; i <= 3; i++ {
+		if status, body := s.login(t, "ana", "Incorrecta-123"); status != 401 {
+			t.Fatalf("fallo %d: estado %d, respuesta %v", i, status, body)
+		}
+	}
+
+	// Bloqueada: ni la contraseña correcta entra
+	status, body := s.login(t, "ana", testPassword)
+	if status != 429 {
+		t.Fatalf("cuenta bloqueada: estado %d, respuesta %v", status, body)
+	}
+	if retryAfter, _ := body["retryAfter"].(float64); retryAfter <= 0 || retryAfter > (15*time.Minute).Seconds() {
+		t.Errorf("retryAfter = %v", body["retryAfter"])
+	}
+
+	// Al vencer el bloqueo vuelve a poder entrar
+	past := time.Now().Add(-time.Second)
+	if err := s.users.SetLoginLockedUntil(context.Background(), "ana@example.com", past); err != nil {
+		t.Fatal(err)
+	}
+	if status, body := s.login(t, "ana", testPassword); status != 200 {
+		t.Fatalf("tras el bloqueo: estado %d, respuesta %v", status, body)
+	}
+}
+
+func TestVerifyOtp(t *testing.T) {
+	s := newTestServer(t)
+	secret := s.createMFAUser(t, "ana@example.com", "ana")
+
+	status, body := s.login(t, "ana", testPassword)
+	if status != 200 || body["requiresMFA"] != true {
+		t.Fatalf("login con MFA: estado %d, respuesta %v", status, body)
+	}
+	if _, ok := body["token"]; ok {
+		t.Fatal("el login con MFA no debe devolver el token antes del segundo paso")
+	}
+	challenge := stringField(t, body, "mfaToken")
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, body = s.post(t, "/verify-otp", map[string]string{"mfaToken": challenge, "token": code})
+	if status != 200 {
+		t.Fatalf("verificar OTP: estado %d, respuesta %v", status, body)
+	}
+	stringField(t, body, "token")
+	stringField(t, body, "refreshToken")
+
+	// El reto es de un solo uso
+	if status, body := s.post(t, "/verify-otp", map[string]string{"mfaToken": challenge, "token": code}); status != 401 {
+		t.Errorf("reto reutilizado: estado %d, respuesta %v", status, body)
+	}
+
+	// Con un reto nuevo, el mismo código tampoco vale otra vez
+	challenge = s.mfaChallenge(t, "ana")
+	if status, body := s.post(t, "/verify-otp", map[string]string{"mfaToken": challenge, "token": code}); status != 401 {
+		t.Errorf("código reutilizado: estado %d, respuesta %v", status, body)
+	}
+}
+
+func TestVerifyOtpLocksAfterRepeatedFailures(t *testing.T) {
+	s := newTestServer(t)
+	secret := s.createMFAUser(t, "ana@example.com", "ana")
+
+	for i := 1; i <= otpMaxFailedAttempts; i++ {
+		challenge := s.mfaChallenge(t, "ana")
+		if status, body := s.post(t, "/verify-otp", map[string]string{"mfaToken": challenge, "token": "000000"}); status != 401 {
+			t.Fatalf("fallo %d: estado %d, respuesta %v", i, status, body)
+		}
+	}
+
+	// Bloqueada: ni un código correcto entra
+	code, err := totp.GenerateCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := s.mfaChallenge(t, "ana")
+	if status, body := s.post(t, "/verify-otp", map[string]string{"mfaToken": challenge, "token": code}); status != 429 {
+		t.Fatalf("OTP bloqueado: estado %d, respuesta %v", status, body)
+	}
+}
+
+// createMFAUser crea un usuario con MFA activo y devuelve su secreto TOTP
+func (s *testServer) createMFAUser(t *testing.T, email string, username string) string {
+	t.Helper()
+
+	key, err := generateTOTPKey(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.createUser(t, email, username, nil)
+	if err := s.users.EnableMFA(context.Background(), email, key.Secret(), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	return key.Secret()
+}
+
+// mfaChallenge hace login con contraseña y devuelve el reto MFA
+func (s *testServer) mfaChallenge(t *testing.T, identifier string) string {
+	t.Helper()
+
+	status, body := s.login(t, identifier, testPassword)
+	if status != 200 {
+		t.Fatalf("login con MFA: estado %d, respuesta %v", status, body)
+	}
+	return stringField(t, body, "mfaToken")
+}
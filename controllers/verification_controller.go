@@ -0,0 +1,160 @@
+// ./controllers/verification_controller.go
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Vigencia del enlace de verificación de email
+const emailVerificationTTL = 24 * time.Hour
+
+// Propósito del token firmado; impide usarlo como access token o viceversa
+const emailVerificationPurpose = "email-verification"
+
+// VerifyEmail marca la cuenta como verificada a partir del enlace firmado
+func (h *Handler) VerifyEmail(c *fiber.Ctx) error {
+	tokenStr := c.Query("token")
+	if tokenStr == "" {
+		h.actions.LogAction("anonymous", "verifyEmail-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Falta el token de verificación"})
+	}
+
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		return h.verificationSigningKey(), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		h.actions.LogAction("anonymous", "verifyEmail-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Enlace de verificación inválido o expirado"})
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	email, _ := claims["email"].(string)
+	if purpose, _ := claims["purpose"].(string); purpose != emailVerificationPurpose || email == "" {
+		h.actions.LogAction("anonymous", "verifyEmail-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Enlace de verificación inválido o expirado"})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = h.users.MarkEmailVerified(ctx, email, time.Now())
+	if err == repositories.ErrNotFound {
+		h.actions.LogAction(email, "verifyEmail-error", "error")(c)
+		return c.Status(404).JSON(fiber.Map{"error": "Usuario no encontrado"})
+	} else if err != nil {
+		h.actions.LogAction(email, "verifyEmail-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al verificar el email"})
+	}
+
+	h.actions.LogAction(email, "verifyEmail", "info")(c)
+	return c.JSON(fiber.Map{"message": "Email verificado con éxito"})
+}
+
+// ResendVerificationEmail vuelve a enviar el enlace de verificación.
+// Responde igual exista o no la cuenta y respeta un intervalo mínimo entre envíos.
+func (h *Handler) ResendVerificationEmail(c *fiber.Ctx) error {
+	type ResendRequest struct {
+		Email string `json:"email"`
+	}
+
+	var req ResendRequest
+	if err := c.BodyParser(&req); err != nil || req.Email == "" {
+		h.actions.LogAction("anonymous", "resendVerification-error", "error")(c)
+		return c.Status(400).JSON(fiber.Map{"error": "Solicitud inválida"})
+	}
+
+	response := fiber.Map{
+		"message": "Si la cuenta existe y no está verificada, recibirás un nuevo enlace",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Reservar el envío de forma atómica: solo cuentas sin verificar y fuera del intervalo mínimo
+	reserved, err := h.users.ReserveVerificationEmail(ctx, req.Email, time.Now(), h.cfg.Auth.VerificationResendInterval)
+	if err != nil {
+		h.actions.LogAction("anonymous", "resendVerification-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al reenviar la verificación"})
+	}
+	if !reserved {
+		h.actions.LogAction("anonymous", "resendVerification", "info")(c)
+		return c.JSON(response)
+	}
+
+	if err := h.sendVerificationEmail(req.Email); err != nil {
+		log.Println("Error al enviar el correo de verificación:", err)
+		h.actions.LogAction(req.Email, "resendVerification-error", "error")(c)
+		return c.Status(500).JSON(fiber.Map{"error": "Error al reenviar la verificación"})
+	}
+
+	h.actions.LogAction(req.Email, "resendVerification", "info")(c)
+	return c.JSON(response)
+}
+
+// sendVerificationEmail envía el enlace firmado de verificación
+func (h *Handler) sendVerificationEmail(email string) error {
+	claims := jwt.MapClaims{
+		"email":   email,
+		"purpose": emailVerificationPurpose,
+		"exp":     time.Now().Add(emailVerificationTTL).Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.verificationSigningKey())
+	if err != nil {
+		return err
+	}
+
+	link := h.cfg.Auth.EmailVerificationURL + "?token=" + url.QueryEscape(token)
+
+	body := fmt.Sprintf(
+		"Gracias por registrarte.\n\n"+
+			"Confirma tu email con el siguiente enlace (válido por %d horas):\n%s",
+		int(emailVerificationTTL.Hours()), link,
+	)
+	return h.mailer.Send(email, "Verifica tu email", body)
+}
+
+// verificationSigningKey deriva una clave distinta de la de los access tokens
+func (h *Handler) verificationSigningKey() []byte {
+	return []byte(h.cfg.Auth.JWTSecret + "|" + emailVerificationPurpose)
+}
+
+// RunUnverifiedAccountCleanup elimina periódicamente las cuentas que nunca se verificaron
+// dentro del plazo configurado (UNVERIFIED_ACCOUNT_TTL, 7 días por defecto) hasta que ctx se cancele
+func (h *Handler) RunUnverifiedAccountCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.cleanupUnverifiedAccounts(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) cleanupUnverifiedAccounts(ctx context.Context) {
+	ttl := h.cfg.Auth.UnverifiedAccountTTL
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	deleted, err := h.users.DeleteUnverifiedBefore(ctx, time.Now().Add(-ttl))
+	if err != nil {
+		log.Println("Error al limpiar cuentas sin verificar:", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Se eliminaron %d cuentas sin verificar", deleted)
+	}
+}
@@ -2,45 +2,91 @@
 package logs
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 )
 
-var Logger *logrus.Logger
-
-func init() {
-	Logger = logrus.New()
-
-	logDir := "log" // Se crea dentro del root del proyecto
+// Logger es un logger de logrus que escribe en archivos; Close los vacía y los cierra
+type Logger struct {
+	*logrus.Logger
+	dir   string
+	files []*os.File
+}
 
+// New crea un logger JSON que escribe en dir: all.log recibe todo, error.log los errores
+// y combined.log los mensajes informativos
+func New(dir string) (*Logger, error) {
 	// Crear el directorio si no existe
-	if _, err := os.Stat(logDir); os.IsNotExist(err) {
-		err := os.MkdirAll(logDir, os.ModePerm)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("no se pudo crear el directorio de logs: %v", err)
+	}
+
+	// Archivos de log; si uno falla se cierran los que ya se abrieron
+	var files []*os.File
+	for _, name := range []string{"error.log", "combined.log", "all.log"} {
+		file, err := openLogFile(filepath.Join(dir, name))
 		if err != nil {
-			logrus.Fatalf("No se pudo crear el directorio de logs: %v", err)
+			for _, opened := range files {
+				opened.Close()
+			}
+			return nil, err
 		}
+		files = append(files, file)
 	}
-
-	// Archivos de log
-	errorLogPath := filepath.Join(logDir, "error.log")
-	combinedLogPath := filepath.Join(logDir, "combined.log")
-	allLogPath := filepath.Join(logDir, "all.log")
-
-	// Crear archivos
-	errorFile, _ := os.OpenFile(errorLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	combinedFile, _ := os.OpenFile(combinedLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	allFile, _ := os.OpenFile(allLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	errorFile, combinedFile, allFile := files[0], files[1], files[2]
 
 	// Configuración de logger
-	Logger.SetFormatter(&logrus.JSONFormatter{})
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
 
-	Logger.SetOutput(allFile) // Por defecto, manda todo a all.log
+	logger.SetOutput(allFile) // Por defecto, manda todo a all.log
 
 	// Agregar múltiples salidas
-	Logger.AddHook(NewFileHook(errorFile, logrus.ErrorLevel))
-	Logger.AddHook(NewFileHook(combinedFile, logrus.InfoLevel))
+	logger.AddHook(NewFileHook(errorFile, logrus.ErrorLevel))
+	logger.AddHook(NewFileHook(combinedFile, logrus.InfoLevel))
+	return &Logger{Logger: logger, dir: dir, files: files}, nil
+}
+
+// Check comprueba que los archivos de log siguen abiertos y que se puede escribir en el directorio
+func (l *Logger) Check() error {
+	for _, file := range l.files {
+		if _, err := file.Stat(); err != nil {
+			return err
+		}
+	}
+
+	probe, err := os.CreateTemp(l.dir, ".health-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
+
+// Close vuelca a disco lo escrito y cierra los archivos; el logger no debe usarse después
+func (l *Logger) Close() error {
+	var errs []error
+	for _, file := range l.files {
+		if err := file.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+		if err := file.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func openLogFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo abrir el archivo de log %s: %v", path, err)
+	}
+	return file, nil
 }
 
 // FileHook permite enviar logs a archivos separados por nivel
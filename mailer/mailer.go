@@ -0,0 +1,56 @@
+// ./mailer/mailer.go
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+)
+
+// Mailer envía correos electrónicos de texto plano
+type Mailer interface {
+	Send(to string, subject string, body string) error
+}
+
+// New crea el Mailer según cfg.Driver: "smtp", "file" o "console" (por defecto)
+func New(cfg config.MailConfig) (Mailer, error) {
+	driver := strings.ToLower(cfg.Driver)
+
+	var m Mailer
+	switch driver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("falta la variable de entorno SMTP_HOST")
+		}
+		m = &SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     strconv.Itoa(cfg.SMTPPort),
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}
+	case "file":
+		fileMailer, err := NewFileMailer(cfg.File, cfg.From)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo abrir el archivo de correos: %v", err)
+		}
+		m = fileMailer
+	case "", "console":
+		m = NewConsoleMailer(cfg.From)
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER desconocido: %s", driver)
+	}
+
+	log.Printf("Envío de correos configurado (driver: %s)", driverName(driver))
+	return m, nil
+}
+
+func driverName(driver string) string {
+	if driver == "" {
+		return "console"
+	}
+	return driver
+}
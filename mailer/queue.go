@@ -0,0 +1,78 @@
+// ./mailer/queue.go
+package mailer
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Queue ejecuta envíos en segundo plano con un número fijo de workers y una cola
+// acotada, para que una ráfaga de peticiones no lance goroutines sin límite.
+// Close espera a los envíos pendientes.
+type Queue struct {
+	tasks   chan queuedTask
+	timeout time.Duration
+	workers sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type queuedTask struct {
+	description string
+	run         func(ctx context.Context) error
+}
+
+// NewQueue arranca workers goroutines que atienden una cola de hasta size envíos;
+// cada envío dispone como máximo de timeout
+func NewQueue(workers int, size int, timeout time.Duration) *Queue {
+	q := &Queue{
+		tasks:   make(chan queuedTask, size),
+		timeout: timeout,
+	}
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+	for task := range q.tasks {
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		if err := task.run(ctx); err != nil {
+			log.Printf("Error en el envío en segundo plano (%s): %v", task.description, err)
+		}
+		cancel()
+	}
+}
+
+// Enqueue añade un envío a la cola. Devuelve false si la cola está llena o cerrada;
+// en ese caso el envío se descarta. Los errores del envío solo quedan en el log.
+func (q *Queue) Enqueue(description string, run func(ctx context.Context) error) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.tasks <- queuedTask{description: description, run: run}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close deja de aceptar envíos y espera a que terminen los que ya están en la cola
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.tasks)
+	}
+	q.mu.Unlock()
+	q.workers.Wait()
+}
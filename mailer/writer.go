@@ -0,0 +1,44 @@
+// ./mailer/writer.go
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WriterMailer escribe los correos en un io.Writer en lugar de enviarlos (desarrollo local)
+type WriterMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	from string
+}
+
+// NewConsoleMailer escribe los correos en la salida estándar
+func NewConsoleMailer(from string) *WriterMailer {
+	return &WriterMailer{w: os.Stdout, from: from}
+}
+
+// NewFileMailer agrega los correos al final del archivo indicado
+func NewFileMailer(path string, from string) (*WriterMailer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &WriterMailer{w: file, from: from}, nil
+}
+
+func (m *WriterMailer) Send(to string, subject string, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "----- %s -----\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), m.from, to, subject, body)
+	return err
+}
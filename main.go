@@ -5,64 +5,87 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"time"
+	"os/signal"
+	"syscall"
 
+	"github.com/Ana-Gabs/actividadr-back/app"
 	"github.com/Ana-Gabs/actividadr-back/config"
-	"github.com/Ana-Gabs/actividadr-back/routes"
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/joho/godotenv"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 // main inicializa y arranca el servidor
 func main() {
-	// Cargar variables de entorno desde .env
-	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error al cargar el archivo .env:", err)
+	// Configuración: valores por defecto, CONFIG_FILE, .env (opcional) y variables de entorno
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Error en la configuración:\n", err)
 	}
+	log.Printf("Configuración efectiva:\n%s", cfg.Redacted())
 
-	// Inicializar conexión con MongoDB
-	if err := config.InitMongoDB(); err != nil {
-		log.Fatal("Error al inicializar MongoDB:", err)
+	// Subcomando de migraciones: solo necesita MongoDB y no arranca el servidor
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		client, db, err := config.ConnectMongo(cfg.Mongo)
+		if err != nil {
+			log.Fatal("Error al inicializar MongoDB:", err)
+		}
+		err = runMigrateCommand(db, cfg.Mongo, os.Args[2:])
+		config.CloseMongo(client)
+		if err != nil {
+			log.Fatal("Error en migraciones: ", err)
+		}
+		return
 	}
-	defer config.CloseMongo() // Cerrar conexión al finalizar
 
-	// Obtener variables de entorno
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3000" // Puerto por defecto si no está definido
+	// Subcomando para asignar roles (p. ej. el primer administrador): solo necesita MongoDB
+	if len(os.Args) > 1 && os.Args[1] == "promote" {
+		client, db, err := config.ConnectMongo(cfg.Mongo)
+		if err != nil {
+			log.Fatal("Error al inicializar MongoDB:", err)
+		}
+		err = runPromoteCommand(db, os.Args[2:])
+		config.CloseMongo(client)
+		if err != nil {
+			log.Fatal("Error al asignar el rol: ", err)
+		}
+		return
 	}
-	ipWebserviceURL := os.Getenv("IP_WEBSERVICE_URL")
-	if ipWebserviceURL == "" {
-		ipWebserviceURL = "localhost" // Valor por defecto
+
+	// Conexión con MongoDB, repositorios, correo y handlers
+	a, err := app.New(cfg)
+	if err != nil {
+		log.Fatal("Error al inicializar la aplicación:", err)
 	}
 
-	// Inicializar la aplicación Fiber
-	app := fiber.New()
+	// Migraciones pendientes antes de crear índices (pueden corregir datos que los violen)
+	if cfg.Mongo.MigrateOnStart {
+		if err := runMigrations(a.DB, cfg.Mongo); err != nil {
+			log.Fatal("Error al aplicar migraciones:", err)
+		}
+	}
 
-	// Middlewares
-	app.Use(logger.New()) // Reemplazo de logMiddleware
-	app.Use(cors.New())   // Habilitar CORS
+	// Crear índices: únicos de usuarios, consultas de logs y TTL (tokens y retención de logs)
+	if err := config.EnsureIndexes(a.DB, cfg.Mongo.LogRetentionDays); err != nil {
+		log.Fatal("Error al crear índices en MongoDB:", err)
+	}
 
-	// Configurar rutas
-	routes.SetupUserRoutes(app)
-	routes.SetupLogsRoutes(app)
+	// Tareas periódicas (limpieza de cuentas sin verificar)
+	a.StartBackgroundJobs()
 
 	// Verificar la conexión con MongoDB
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	collections, err := config.MongoClient.Database("actividadr-back").ListCollectionNames(ctx, bson.M{})
+	collections, err := a.Collections()
 	if err != nil {
 		log.Fatal("Error al conectar con MongoDB:", err)
 	}
-	fmt.Println("Conexión con MongoDB establecida correctamente. Colecciones encontradas:", collections)
+	fmt.Printf("Conexión con MongoDB establecida correctamente (%s). Colecciones encontradas: %v\n", cfg.Mongo.Database, collections)
+
+	// Iniciar el servidor hasta recibir SIGINT o SIGTERM; después se terminan las
+	// peticiones en curso y se cierran los logs y la conexión con MongoDB
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Iniciar el servidor
-	listenAddr := fmt.Sprintf("%s:%s", ipWebserviceURL, port)
-	if err := app.Listen(listenAddr); err != nil {
-		log.Fatal("Error al iniciar el servidor:", err)
+	err = a.Run(ctx)
+	a.Close()
+	if err != nil {
+		log.Fatal("Error en el servidor:", err)
 	}
-	fmt.Printf("Servidor escuchando en http://%s\n", listenAddr)
+	log.Println("Servidor detenido")
 }
@@ -0,0 +1,132 @@
+// ./metrics/metrics.go
+package metrics
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Ruta que se usa como etiqueta cuando la petición no coincide con ninguna ruta
+const unmatchedRoute = "unmatched"
+
+// Clave de c.Locals con la que el rate limiter marca las peticiones rechazadas
+const rateLimitedLocal = "metrics.rateLimited"
+
+// Resultados de un intento de login (auth_login_attempts_total)
+const (
+	LoginSuccess     = "success"
+	LoginMFARequired = "mfa_required"
+	LoginFailure     = "failure"
+	LoginLocked      = "locked"
+	LoginDenied      = "denied"
+	LoginBadRequest  = "bad_request"
+	LoginError       = "error"
+)
+
+// Resultados de una verificación OTP (auth_otp_verifications_total)
+const (
+	OTPSuccess          = "success"
+	OTPRecovery         = "recovery_code"
+	OTPInvalid          = "invalid"
+	OTPInvalidChallenge = "invalid_challenge"
+	OTPLocked           = "locked"
+	OTPDenied           = "denied"
+	OTPBadRequest       = "bad_request"
+	OTPError            = "error"
+)
+
+// Metrics agrupa las métricas de una instancia en un registro propio, así que
+// pueden coexistir varias instancias (p. ej. en pruebas)
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	rateLimited      *prometheus.CounterVec
+	logins           *prometheus.CounterVec
+	otpVerifications *prometheus.CounterVec
+	mongoDuration    *prometheus.HistogramVec
+}
+
+// New crea y registra las métricas, junto con las del runtime de Go y del proceso
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Peticiones HTTP atendidas, por ruta, método y código de estado.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duración de las peticiones HTTP, por ruta, método y código de estado.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_rate_limited_total",
+			Help: "Peticiones rechazadas por el rate limiter, por ruta.",
+		}, []string{"route"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_login_attempts_total",
+			Help: "Intentos de login por resultado.",
+		}, []string{"outcome"}),
+		otpVerifications: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_otp_verifications_total",
+			Help: "Verificaciones del segundo factor por resultado.",
+		}, []string{"outcome"}),
+		mongoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mongodb_command_duration_seconds",
+			Help:    "Duración de los comandos enviados a MongoDB, por comando y resultado.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}, []string{"command", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.rateLimited,
+		m.logins,
+		m.otpVerifications,
+		m.mongoDuration,
+	)
+	return m
+}
+
+// Handler expone las métricas en el formato de texto de Prometheus
+func (m *Metrics) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+}
+
+// LoginAttempt cuenta un intento de login con uno de los resultados Login*
+func (m *Metrics) LoginAttempt(outcome string) {
+	m.logins.WithLabelValues(outcome).Inc()
+}
+
+// OTPVerification cuenta una verificación del segundo factor con uno de los resultados OTP*
+func (m *Metrics) OTPVerification(outcome string) {
+	m.otpVerifications.WithLabelValues(outcome).Inc()
+}
+
+// MarkRateLimited marca la petición como rechazada por el rate limiter; Middleware la cuenta
+func MarkRateLimited(c *fiber.Ctx) {
+	c.Locals(rateLimitedLocal, true)
+}
+
+// statusCode devuelve el código con el que se responderá: si el handler devolvió un
+// error, el ErrorHandler de Fiber aún no lo ha escrito en la respuesta
+func statusCode(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,36 @@
+// ./metrics/middleware.go
+package metrics
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware mide cada petición HTTP. La etiqueta route es el patrón de la ruta
+// (p. ej. /admin/users/:email), no la URL, para no disparar la cardinalidad.
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+		duration := time.Since(start)
+
+		status := statusCode(c, err)
+		route := c.Route().Path
+		if status == fiber.StatusNotFound && route == "/" {
+			// Ninguna ruta coincidió; Route() es la de este middleware
+			route = unmatchedRoute
+		}
+		// Fiber reutiliza el buffer de Method(); la etiqueta necesita su propia copia
+		labels := []string{strings.Clone(route), strings.Clone(c.Method()), strconv.Itoa(status)}
+
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpDuration.WithLabelValues(labels...).Observe(duration.Seconds())
+		if limited, _ := c.Locals(rateLimitedLocal).(bool); limited {
+			m.rateLimited.WithLabelValues(labels[0]).Inc()
+		}
+		return err
+	}
+}
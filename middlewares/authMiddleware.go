@@ -2,41 +2,71 @@
 package middlewares
 
 import (
-	"os"
+	"context"
 	"strings"
+	"time"
 
+	"github.com/Ana-Gabs/actividadr-back/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// AuthMiddleware valida el JWT firmado con jwtSecret y rechaza los tokens revocados
+func AuthMiddleware(jwtSecret string, revocations *utils.RevocationStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
 
-func AuthMiddleware(c *fiber.Ctx) error {
-	authHeader := c.Get("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"message": "Token inválido",
+			})
+		}
 
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"message": "Token inválido",
+		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+
+		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+			return []byte(jwtSecret), nil
 		})
-	}
 
-	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+		if err != nil || !token.Valid {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token inválido o expirado",
+			})
+		}
 
+		claims, ok := token.Claims.(jwt.MapClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token inválido o expirado",
+			})
+		}
 
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		return []byte(os.Getenv("JWT_SECRET")), nil
-	})
+		// Todo token emitido lleva jti; sin él no se puede comprobar la revocación
+		jti, _ := claims["jti"].(string)
+		email, _ := claims["email"].(string)
+		issuedAt, err := utils.TokenIssuedAt(claims)
+		if jti == "" || err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token inválido o expirado",
+			})
+		}
 
-	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"message": "Token inválido o expirado",
-		})
-	}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		revoked, err := revocations.IsTokenRevoked(ctx, jti, email, issuedAt)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"message": "Error al validar el token",
+			})
+		}
+		if revoked {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token revocado",
+			})
+		}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok {
 		c.Locals("user", claims)
+		return c.Next()
 	}
-
-	return c.Next()
 }
-
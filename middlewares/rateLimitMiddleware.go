@@ -5,6 +5,7 @@ package middlewares
 import (
 	"time"
 
+	"github.com/Ana-Gabs/actividadr-back/metrics"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
@@ -14,6 +15,21 @@ func RateLimitMiddleware() fiber.Handler {
 		Max:        100,
 		Expiration: 10 * time.Minute,
 		LimitReached: func(c *fiber.Ctx) error {
+			metrics.MarkRateLimited(c)
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"message": "Demasiadas peticiones, intenta más tarde.",
+			})
+		},
+	})
+}
+
+// StrictRateLimitMiddleware limita por IP con un máximo propio, para endpoints sensibles como el reenvío de correos
+func StrictRateLimitMiddleware(max int, expiration time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: expiration,
+		LimitReached: func(c *fiber.Ctx) error {
+			metrics.MarkRateLimited(c)
 			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
 				"message": "Demasiadas peticiones, intenta más tarde.",
 			})
@@ -0,0 +1,31 @@
+// ./middleware/roleMiddleware.go
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RequireRole permite el paso solo si el rol del JWT es alguno de los indicados.
+// Debe registrarse después de AuthMiddleware, que guarda los claims en c.Locals("user").
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(jwt.MapClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token inválido",
+			})
+		}
+
+		role, _ := claims["role"].(string)
+		for _, allowed := range roles {
+			if role == allowed {
+				return c.Next()
+			}
+		}
+
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"message": "No tienes permisos para acceder a este recurso",
+		})
+	}
+}
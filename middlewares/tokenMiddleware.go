@@ -0,0 +1,25 @@
+// ./middleware/tokenMiddleware.go
+package middlewares
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StaticTokenMiddleware exige el token fijo expectedToken en la cabecera
+// "Authorization: Bearer ...". Es para clientes automáticos (p. ej. el scraper de
+// Prometheus) que no pueden renovar un JWT. La comparación es en tiempo constante.
+func StaticTokenMiddleware(expectedToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		authHeader := c.Get("Authorization")
+		token, found := strings.CutPrefix(authHeader, "Bearer ")
+		if !found || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"message": "Token inválido",
+			})
+		}
+		return c.Next()
+	}
+}
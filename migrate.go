@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/migrations"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Uso del subcomando de migraciones
+const migrateUsage = "uso: migrate up | migrate down [pasos] | migrate status"
+
+// runMigrations aplica las migraciones pendientes al iniciar el servidor
+func runMigrations(db *mongo.Database, cfg config.MongoConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MigrationTimeout)
+	defer cancel()
+
+	runner, err := migrations.NewRunner(db, migrations.All())
+	if err != nil {
+		return err
+	}
+	_, err = runner.Up(ctx)
+	return err
+}
+
+// runMigrateCommand ejecuta el subcomando "migrate" (up, down [pasos] o status)
+func runMigrateCommand(db *mongo.Database, cfg config.MongoConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MigrationTimeout)
+	defer cancel()
+
+	runner, err := migrations.NewRunner(db, migrations.All())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := runner.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No hay migraciones pendientes")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("número de pasos inválido: %s", args[1])
+			}
+		}
+		reverted, err := runner.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			fmt.Println("No hay migraciones aplicadas")
+		}
+		return nil
+
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pendiente"
+			if s.Applied {
+				state = "aplicada " + s.AppliedAt.Local().Format(time.RFC3339)
+			}
+			if !s.Registered {
+				state += " (desconocida en este binario)"
+			}
+			fmt.Printf("%4d  %-40s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,37 @@
+// ./migrations/0001_backfill_user_defaults.go
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// backfillUserDefaults completa los campos que no tienen las cuentas creadas antes de
+// los roles, del enrolamiento MFA y de la verificación de email. Es irreversible:
+// después no se distingue qué valores se completaron y cuáles ya existían.
+var backfillUserDefaults = Migration{
+	Version: 1,
+	Name:    "backfill_user_defaults",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		defaults := bson.D{
+			{Key: "role", Value: "user"},
+			{Key: "mfaEnabled", Value: false},
+			// Las cuentas anteriores a la verificación se consideran verificadas
+			{Key: "email_verified", Value: true},
+		}
+
+		users := db.Collection("users")
+		for _, field := range defaults {
+			_, err := users.UpdateMany(ctx,
+				bson.M{field.Key: bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{field.Key: field.Value}},
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,122 @@
+// ./migrations/0002_resolve_case_duplicates.go
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collation de los índices únicos de "users" (config.UserCollation); se copia aquí
+// para que la migración no cambie si esa cambia
+var caseInsensitive = &options.Collation{Locale: "en", Strength: 2}
+
+// resolveCaseDuplicates prepara "users" para los índices únicos que no distinguen
+// mayúsculas. Los nombres de usuario repetidos se renombran en las cuentas más nuevas
+// (nombre-2, nombre-3...). Los emails repetidos no se pueden resolver sin decidir qué
+// cuenta conservar: la migración falla y los lista para que se resuelvan a mano.
+// Es irreversible: los nombres originales no se guardan.
+var resolveCaseDuplicates = Migration{
+	Version: 2,
+	Name:    "resolve_case_duplicates",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		users := db.Collection("users")
+
+		// Primero los emails, para no modificar nada si la migración no puede terminar
+		emails, err := findCaseDuplicates(ctx, users, "email")
+		if err != nil {
+			return err
+		}
+		if len(emails) > 0 {
+			conflicts := make([]string, 0, len(emails))
+			for _, group := range emails {
+				conflicts = append(conflicts, group.String())
+			}
+			return fmt.Errorf(
+				"hay %d emails repetidos que solo difieren en mayúsculas; elimina o cambia el email de las cuentas sobrantes y vuelve a ejecutar las migraciones: %s",
+				len(emails), strings.Join(conflicts, "; "),
+			)
+		}
+
+		usernames, err := findCaseDuplicates(ctx, users, "username")
+		if err != nil {
+			return err
+		}
+		for _, group := range usernames {
+			// Conserva el nombre la cuenta más antigua
+			for _, account := range group.Accounts[1:] {
+				renamed, err := freeUsername(ctx, users, account.Value)
+				if err != nil {
+					return err
+				}
+				_, err = users.UpdateOne(ctx, bson.M{"_id": account.ID}, bson.M{"$set": bson.M{"username": renamed}})
+				if err != nil {
+					return err
+				}
+				log.Printf("Nombre de usuario repetido: %q de la cuenta %v pasa a %q", account.Value, account.ID, renamed)
+			}
+		}
+		return nil
+	},
+}
+
+// caseDuplicate es un valor que comparten varias cuentas, de la más antigua a la más nueva
+type caseDuplicate struct {
+	Accounts []struct {
+		ID    interface{} `bson:"id"`
+		Value string      `bson:"value"`
+	} `bson:"accounts"`
+}
+
+func (d caseDuplicate) String() string {
+	accounts := make([]string, 0, len(d.Accounts))
+	for _, account := range d.Accounts {
+		accounts = append(accounts, fmt.Sprintf("%s (%v)", account.Value, account.ID))
+	}
+	return strings.Join(accounts, ", ")
+}
+
+// findCaseDuplicates agrupa las cuentas por field sin distinguir mayúsculas y devuelve
+// los grupos con más de una cuenta
+func findCaseDuplicates(ctx context.Context, users *mongo.Collection, field string) ([]caseDuplicate, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{field: bson.M{"$type": "string"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "date_register", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$" + field,
+			"count":    bson.M{"$sum": 1},
+			"accounts": bson.M{"$push": bson.M{"id": "$_id", "value": "$" + field}},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+	}
+
+	cursor, err := users.Aggregate(ctx, pipeline, options.Aggregate().SetCollation(caseInsensitive))
+	if err != nil {
+		return nil, err
+	}
+	var duplicates []caseDuplicate
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
+
+// freeUsername devuelve el primer nombre libre de la forma username-2, username-3...
+func freeUsername(ctx context.Context, users *mongo.Collection, username string) (string, error) {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s-%d", username, n)
+		count, err := users.CountDocuments(ctx, bson.M{"username": candidate},
+			options.Count().SetCollation(caseInsensitive).SetLimit(1))
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// ./migrations/migration.go
+package migrations
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration es un cambio de esquema versionado. Una vez publicada, una migración
+// no debe cambiar de versión ni de contenido: los cambios nuevos van en otra migración.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	// Down revierte Up; nil marca la migración como irreversible
+	Down func(ctx context.Context, db *mongo.Database) error
+}
+
+// registry contiene todas las migraciones de la aplicación
+var registry = []Migration{
+	backfillUserDefaults,
+	resolveCaseDuplicates,
+}
+
+// All devuelve las migraciones registradas ordenadas por versión
+func All() []Migration {
+	sorted := append([]Migration(nil), registry...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
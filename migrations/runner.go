@@ -0,0 +1,297 @@
+// ./migrations/runner.go
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Colección con una entrada por migración aplicada (_id = versión)
+const migrationsCollection = "schema_migrations"
+
+// Colección con el candado que impide que dos instancias migren a la vez
+const lockCollection = "schema_migrations_lock"
+
+const lockID = "migrations"
+
+// Vigencia del candado; se renueva antes de cada migración y, si una instancia
+// muere sin liberarlo, otra puede tomarlo al vencer
+const lockTTL = 10 * time.Minute
+
+// Espera entre intentos de tomar el candado
+const lockRetryInterval = 2 * time.Second
+
+// ErrIrreversible se devuelve al intentar revertir una migración sin Down
+var ErrIrreversible = errors.New("la migración no se puede revertir")
+
+// ErrLockLost indica que el candado venció o lo tomó otra instancia durante la ejecución
+var ErrLockLost = errors.New("se perdió el candado de migraciones")
+
+// Status describe una migración y si ya está aplicada
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	// Registered es false si la migración está aplicada pero este binario no la conoce
+	Registered bool `json:"registered"`
+}
+
+type record struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Runner aplica y revierte migraciones sobre una base de datos
+type Runner struct {
+	db         *mongo.Database
+	migrations []Migration
+	owner      string
+}
+
+// NewRunner valida las migraciones (versiones positivas y únicas) y crea el runner
+func NewRunner(db *mongo.Database, migrations []Migration) (*Runner, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i, m := range sorted {
+		if m.Version <= 0 || m.Name == "" || m.Up == nil {
+			return nil, fmt.Errorf("migración inválida: versión %d %q", m.Version, m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("versión de migración duplicada: %d", m.Version)
+		}
+	}
+
+	owner, err := lockOwner()
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: sorted, owner: owner}, nil
+}
+
+// Up aplica en orden las migraciones pendientes y devuelve las que aplicó
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := r.withLock(ctx, func() error {
+		done, err := r.appliedRecords(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			if err := r.extendLock(ctx); err != nil {
+				return err
+			}
+			if err := m.Up(ctx, r.db); err != nil {
+				return fmt.Errorf("migración %d (%s): %v", m.Version, m.Name, err)
+			}
+			_, err := r.db.Collection(migrationsCollection).InsertOne(ctx, record{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			})
+			if err != nil {
+				return fmt.Errorf("error registrando la migración %d: %v", m.Version, err)
+			}
+			log.Printf("Migración %d aplicada: %s", m.Version, m.Name)
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down revierte las últimas steps migraciones aplicadas, de la más reciente a la más antigua
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := r.withLock(ctx, func() error {
+		done, err := r.appliedRecords(ctx)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+		if steps < len(versions) {
+			versions = versions[:steps]
+		}
+
+		for _, version := range versions {
+			m, ok := r.find(version)
+			if !ok {
+				return fmt.Errorf("la migración %d (%s) no está registrada en este binario", version, done[version].Name)
+			}
+			if m.Down == nil {
+				return fmt.Errorf("%w: %d (%s)", ErrIrreversible, m.Version, m.Name)
+			}
+			if err := r.extendLock(ctx); err != nil {
+				return err
+			}
+			if err := m.Down(ctx, r.db); err != nil {
+				return fmt.Errorf("revirtiendo la migración %d (%s): %v", m.Version, m.Name, err)
+			}
+			if _, err := r.db.Collection(migrationsCollection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+				return fmt.Errorf("error registrando la reversión de la migración %d: %v", m.Version, err)
+			}
+			log.Printf("Migración %d revertida: %s", m.Version, m.Name)
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lista las migraciones registradas y las aplicadas, ordenadas por versión
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	done, err := r.appliedRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := Status{Version: m.Version, Name: m.Name, Registered: true}
+		if rec, ok := done[m.Version]; ok {
+			appliedAt := rec.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			delete(done, m.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, rec := range done {
+		appliedAt := rec.AppliedAt
+		statuses = append(statuses, Status{Version: rec.Version, Name: rec.Name, Applied: true, AppliedAt: &appliedAt})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+func (r *Runner) find(version int) (Migration, bool) {
+	for _, m := range r.migrations {
+		if m.Version == version {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (r *Runner) appliedRecords(ctx context.Context) (map[int]record, error) {
+	cursor, err := r.db.Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	done := make(map[int]record, len(records))
+	for _, rec := range records {
+		done[rec.Version] = rec
+	}
+	return done, nil
+}
+
+// withLock ejecuta fn con el candado tomado, esperando mientras lo tenga otra instancia
+func (r *Runner) withLock(ctx context.Context, fn func() error) error {
+	for {
+		acquired, err := r.acquireLock(ctx)
+		if err != nil {
+			return fmt.Errorf("error tomando el candado de migraciones: %v", err)
+		}
+		if acquired {
+			break
+		}
+
+		log.Println("Otra instancia está aplicando migraciones, esperando...")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no se pudo tomar el candado de migraciones: %v", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	defer r.releaseLock()
+	return fn()
+}
+
+// acquireLock toma el candado si no existe o si venció. Si otra instancia lo tiene,
+// el upsert choca con el _id existente y se devuelve false.
+func (r *Runner) acquireLock(ctx context.Context) (bool, error) {
+	now := time.Now()
+	_, err := r.db.Collection(lockCollection).UpdateOne(ctx,
+		bson.M{"_id": lockID, "locked_until": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{
+			"owner":        r.owner,
+			"locked_at":    now,
+			"locked_until": now.Add(lockTTL),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// extendLock renueva el candado; falla si ya no pertenece a esta instancia
+func (r *Runner) extendLock(ctx context.Context) error {
+	result, err := r.db.Collection(lockCollection).UpdateOne(ctx,
+		bson.M{"_id": lockID, "owner": r.owner},
+		bson.M{"$set": bson.M{"locked_until": time.Now().Add(lockTTL)}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// releaseLock libera el candado aunque el contexto de la ejecución ya haya vencido
+func (r *Runner) releaseLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := r.db.Collection(lockCollection).DeleteOne(ctx, bson.M{"_id": lockID, "owner": r.owner})
+	if err != nil {
+		log.Println("Error al liberar el candado de migraciones:", err)
+	}
+}
+
+// lockOwner identifica a esta instancia en el candado
+func lockOwner() (string, error) {
+	hostname, _ := os.Hostname()
+	suffix, err := utils.GenerateRandomToken(8)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), suffix), nil
+}
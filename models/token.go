@@ -0,0 +1,18 @@
+// ./models/token.go
+package models
+
+import "time"
+
+// RefreshToken es un documento de la colección "refresh_tokens". Solo se guarda el hash
+// del token; los tokens de un mismo inicio de sesión comparten FamilyID.
+type RefreshToken struct {
+	TokenHash string     `bson:"token_hash"`
+	Email     string     `bson:"email"`
+	FamilyID  string     `bson:"family_id"`
+	Used      bool       `bson:"used"`
+	UsedAt    *time.Time `bson:"used_at,omitempty"`
+	Revoked   bool       `bson:"revoked"`
+	RevokedAt *time.Time `bson:"revoked_at,omitempty"`
+	CreatedAt time.Time  `bson:"created_at"`
+	ExpiresAt time.Time  `bson:"expires_at"`
+}
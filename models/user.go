@@ -0,0 +1,56 @@
+// ./models/user.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User es un documento de la colección "users".
+// Los campos secretos no se serializan a JSON, así que el struct puede devolverse tal cual al cliente.
+type User struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Email    string             `bson:"email" json:"email"`
+	Username string             `bson:"username" json:"username"`
+	Password string             `bson:"password" json:"-"`
+	Role     string             `bson:"role,omitempty" json:"role"`
+
+	// MFA (TOTP)
+	MFAEnabled       bool     `bson:"mfaEnabled" json:"mfaEnabled"`
+	MFASecret        string   `bson:"mfa_secret,omitempty" json:"-"`
+	MFAPendingSecret string   `bson:"mfa_pending_secret,omitempty" json:"-"`
+	MFARecoveryCodes []string `bson:"mfa_recovery_codes,omitempty" json:"-"`
+	MFALastStep      int64    `bson:"mfa_last_step,omitempty" json:"-"`
+
+	// Verificación de email; nil en cuentas creadas antes de exigirla
+	EmailVerified      *bool      `bson:"email_verified,omitempty" json:"email_verified,omitempty"`
+	EmailVerifiedAt    *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	VerificationSentAt *time.Time `bson:"verification_sent_at,omitempty" json:"-"`
+
+	// Estado de la cuenta
+	Disabled              bool       `bson:"disabled,omitempty" json:"disabled"`
+	DisabledAt            *time.Time `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	PasswordResetRequired bool       `bson:"password_reset_required,omitempty" json:"password_reset_required,omitempty"`
+	PasswordChangedAt     *time.Time `bson:"password_changed_at,omitempty" json:"password_changed_at,omitempty"`
+
+	// Bloqueos por intentos fallidos
+	LoginFailedAttempts int        `bson:"login_failed_attempts,omitempty" json:"login_failed_attempts,omitempty"`
+	LoginLockedUntil    *time.Time `bson:"login_locked_until,omitempty" json:"login_locked_until,omitempty"`
+	OTPFailedAttempts   int        `bson:"otp_failed_attempts,omitempty" json:"otp_failed_attempts,omitempty"`
+	OTPLockedUntil      *time.Time `bson:"otp_locked_until,omitempty" json:"otp_locked_until,omitempty"`
+
+	DateRegister time.Time  `bson:"date_register" json:"date_register"`
+	LastLogin    *time.Time `bson:"last_login" json:"last_login"`
+}
+
+// IsEmailVerified indica si la cuenta puede considerarse verificada
+// (las cuentas anteriores a la verificación no tienen el campo y cuentan como verificadas)
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerified == nil || *u.EmailVerified
+}
+
+// RemainingRecoveryCodes devuelve cuántos códigos de recuperación quedan sin usar
+func (u *User) RemainingRecoveryCodes() int {
+	return len(u.MFARecoveryCodes)
+}
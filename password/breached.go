@@ -0,0 +1,85 @@
+// ./password/breached.go
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Longitud del prefijo del hash SHA-1 con el que se agrupan los hashes (como en el
+// modelo de k-anonimato de Have I Been Pwned); así la búsqueda solo recorre un bucket
+const breachedPrefixLength = 5
+
+// BreachedList es una lista local de hashes SHA-1 de contraseñas filtradas
+type BreachedList struct {
+	buckets map[string]map[string]struct{}
+	count   int
+}
+
+// LoadBreachedList lee un archivo con un hash SHA-1 en hexadecimal por línea.
+// Acepta el formato de Have I Been Pwned ("HASH:CONTEO") e ignora líneas vacías o con "#".
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{buckets: make(map[string]map[string]struct{})}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+		hash := strings.ToUpper(line)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("%s:%d: hash SHA-1 inválido", path, lineNumber)
+		}
+		list.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (l *BreachedList) add(hash string) {
+	prefix, suffix := hash[:breachedPrefixLength], hash[breachedPrefixLength:]
+	bucket, ok := l.buckets[prefix]
+	if !ok {
+		bucket = make(map[string]struct{})
+		l.buckets[prefix] = bucket
+	}
+	if _, exists := bucket[suffix]; !exists {
+		bucket[suffix] = struct{}{}
+		l.count++
+	}
+}
+
+// Contains indica si la contraseña aparece en la lista
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	bucket, ok := l.buckets[hash[:breachedPrefixLength]]
+	if !ok {
+		return false
+	}
+	_, found := bucket[hash[breachedPrefixLength:]]
+	return found
+}
+
+// Len devuelve el número de hashes distintos de la lista
+func (l *BreachedList) Len() int {
+	return l.count
+}
@@ -0,0 +1,156 @@
+// ./password/hash.go
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritmos de hash soportados. El hash guardado identifica su algoritmo y
+// parámetros (formato PHC para Argon2id, formato $2a$ para bcrypt).
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrUnknownHash se devuelve cuando el hash guardado no tiene un formato reconocido
+var ErrUnknownHash = errors.New("formato de hash de contraseña desconocido")
+
+// HashParams son los parámetros con los que se generan los hashes nuevos
+type HashParams struct {
+	Algorithm string
+
+	BcryptCost int
+
+	Argon2Time       uint32
+	Argon2MemoryKiB  uint32
+	Argon2Threads    uint8
+	Argon2KeyLength  uint32
+	Argon2SaltLength uint32
+}
+
+// Longitudes de la clave y de la sal de los hashes Argon2id nuevos
+const (
+	DefaultArgon2KeyLength  = 32
+	DefaultArgon2SaltLength = 16
+)
+
+// Longitud máxima en bytes que bcrypt tiene en cuenta
+const bcryptMaxBytes = 72
+
+// MaxPasswordBytes devuelve cuántos bytes de la contraseña usa el algoritmo (0 sin límite)
+func (p HashParams) MaxPasswordBytes() int {
+	if p.Algorithm == AlgorithmBcrypt {
+		return bcryptMaxBytes
+	}
+	return 0
+}
+
+// Hash genera el hash de la contraseña con estos parámetros
+func (p HashParams) Hash(password string) (string, error) {
+	switch p.Algorithm {
+	case AlgorithmBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	case AlgorithmArgon2id:
+		salt := make([]byte, p.Argon2SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, p.Argon2Time, p.Argon2MemoryKiB, p.Argon2Threads, p.Argon2KeyLength)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, p.Argon2MemoryKiB, p.Argon2Time, p.Argon2Threads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	default:
+		return "", fmt.Errorf("algoritmo de hash no soportado: %s", p.Algorithm)
+	}
+}
+
+// Verify comprueba la contraseña contra el hash guardado con cualquiera de los algoritmos soportados
+func (p HashParams) Verify(password string, encoded string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		stored, err := parseArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		key := argon2.IDKey([]byte(password), stored.salt, stored.time, stored.memory, stored.threads, uint32(len(stored.key)))
+		if subtle.ConstantTimeCompare(key, stored.key) != 1 {
+			return false, false, nil
+		}
+		needsRehash := p.Algorithm != AlgorithmArgon2id ||
+			stored.version != argon2.Version ||
+			stored.time != p.Argon2Time ||
+			stored.memory != p.Argon2MemoryKiB ||
+			stored.threads != p.Argon2Threads ||
+			uint32(len(stored.key)) != p.Argon2KeyLength
+		return true, needsRehash, nil
+
+	case strings.HasPrefix(encoded, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		} else if err != nil {
+			return false, false, err
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return false, false, err
+		}
+		needsRehash := p.Algorithm != AlgorithmBcrypt || cost != p.BcryptCost
+		return true, needsRehash, nil
+
+	default:
+		return false, false, ErrUnknownHash
+	}
+}
+
+type argon2idHash struct {
+	version int
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// parseArgon2id interpreta un hash en formato PHC: $argon2id$v=19$m=...,t=...,p=...$sal$hash
+func parseArgon2id(encoded string) (*argon2idHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return nil, ErrUnknownHash
+	}
+
+	var h argon2idHash
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &h.version); err != nil {
+		return nil, ErrUnknownHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.memory, &h.time, &h.threads); err != nil {
+		return nil, ErrUnknownHash
+	}
+	// argon2.IDKey entra en pánico con 0 iteraciones o 0 hilos
+	if h.memory == 0 || h.time == 0 || h.threads == 0 {
+		return nil, ErrUnknownHash
+	}
+
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrUnknownHash
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, ErrUnknownHash
+	}
+	return &h, nil
+}
@@ -0,0 +1,14 @@
+// ./password/passwords.go
+package password
+
+// Passwords agrupa la política y los parámetros de hash con los que una instancia de la
+// aplicación valida, guarda y comprueba contraseñas
+type Passwords struct {
+	Policy Policy
+	Hash   HashParams
+}
+
+// Validate devuelve las reglas de la política que la contraseña incumple
+func (p Passwords) Validate(password string, username string, email string) []Violation {
+	return p.Policy.Validate(password, username, email)
+}
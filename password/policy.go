@@ -0,0 +1,123 @@
+// ./password/policy.go
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// Conjunto de contraseñas comunes cargado desde el archivo embebido
+var commonPasswords = loadCommonPasswords(commonPasswordsFile)
+
+// Violation describe una regla de la política que la contraseña no cumple
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Policy define los requisitos que debe cumplir una contraseña
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Longitud máxima en bytes que admite el algoritmo de hash (72 con bcrypt); 0 sin límite
+	MaxBytes int
+	// Lista de contraseñas filtradas; nil si no hay ninguna configurada
+	Breached *BreachedList
+}
+
+// Validate devuelve todas las reglas que la contraseña incumple (vacío si es válida)
+func (p Policy) Validate(password string, username string, email string) []Violation {
+	violations := []Violation{}
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		violations = append(violations, Violation{"min_length", "Debe tener al menos " + strconv.Itoa(p.MinLength) + " caracteres"})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{"max_length", "No puede tener más de " + strconv.Itoa(p.MaxLength) + " caracteres"})
+	} else if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, Violation{"max_length", "No puede ocupar más de " + strconv.Itoa(p.MaxBytes) + " bytes"})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, Violation{"uppercase", "Debe incluir al menos una letra mayúscula"})
+	}
+	if p.RequireLower && !hasLower {
+		violations = append(violations, Violation{"lowercase", "Debe incluir al menos una letra minúscula"})
+	}
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, Violation{"digit", "Debe incluir al menos un número"})
+	}
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, Violation{"symbol", "Debe incluir al menos un símbolo"})
+	}
+
+	lower := strings.ToLower(password)
+	if _, ok := commonPasswords[lower]; ok {
+		violations = append(violations, Violation{"common", "Es una contraseña demasiado común"})
+	}
+
+	if similarToIdentity(lower, username, email) {
+		violations = append(violations, Violation{"similarity", "No puede parecerse a tu nombre de usuario o email"})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, Violation{"breached", "Esta contraseña aparece en filtraciones de datos conocidas"})
+	}
+
+	return violations
+}
+
+// similarToIdentity detecta contraseñas que contienen (o están contenidas en) el usuario o el email
+func similarToIdentity(lowerPassword string, username string, email string) bool {
+	candidates := []string{strings.ToLower(username), strings.ToLower(email)}
+	if at := strings.Index(email, "@"); at > 0 {
+		candidates = append(candidates, strings.ToLower(email[:at]))
+	}
+
+	for _, candidate := range candidates {
+		if len(candidate) < 3 {
+			continue
+		}
+		if strings.Contains(lowerPassword, candidate) || strings.Contains(candidate, lowerPassword) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadCommonPasswords(data string) map[string]struct{} {
+	set := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[strings.ToLower(line)] = struct{}{}
+	}
+	return set
+}
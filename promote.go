@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/repositories"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Uso del subcomando para asignar roles
+const promoteUsage = "uso: promote <email> [admin|auditor|user]"
+
+// runPromoteCommand ejecuta el subcomando "promote": asigna un rol (admin por defecto) a
+// una cuenta existente. Es la forma de crear el primer administrador, ya que los
+// endpoints de roles exigen uno.
+func runPromoteCommand(db *mongo.Database, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return errors.New(promoteUsage)
+	}
+	email := args[0]
+	role := utils.RoleAdmin
+	if len(args) == 2 {
+		role = args[1]
+	}
+	if !utils.IsValidRole(role) {
+		return fmt.Errorf("rol inválido: %s (%s)", role, promoteUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	users := repositories.NewMongoUserRepository(db.Collection("users"))
+	err := users.SetRole(ctx, email, role)
+	if err == repositories.ErrNotFound {
+		return fmt.Errorf("no existe ninguna cuenta con el email %s", email)
+	} else if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rol de %s actualizado a %s; el nuevo rol se aplica al volver a iniciar sesión\n", email, role)
+	return nil
+}
@@ -0,0 +1,209 @@
+// ./repositories/token_memory.go
+package repositories
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+)
+
+var (
+	_ RefreshTokenRepository  = (*MemoryRefreshTokenRepository)(nil)
+	_ MFAChallengeRepository  = (*MemoryMFAChallengeRepository)(nil)
+	_ PasswordResetRepository = (*MemoryPasswordResetRepository)(nil)
+)
+
+// MemoryRefreshTokenRepository implementa RefreshTokenRepository en memoria (pruebas y desarrollo sin MongoDB)
+type MemoryRefreshTokenRepository struct {
+	mu sync.Mutex
+	// Indexado por el hash del token
+	tokens map[string]*models.RefreshToken
+}
+
+// NewMemoryRefreshTokenRepository crea un repositorio en memoria vacío
+func NewMemoryRefreshTokenRepository() *MemoryRefreshTokenRepository {
+	return &MemoryRefreshTokenRepository{tokens: make(map[string]*models.RefreshToken)}
+}
+
+func (r *MemoryRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *token
+	r.tokens[token.TokenHash] = &stored
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) Redeem(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok || token.Used || token.Revoked || !token.ExpiresAt.After(now) {
+		return nil, ErrTokenNotFound
+	}
+	token.Used = true
+	token.UsedAt = &now
+
+	// Igual que FindOneAndUpdate sin ReturnDocument: el documento antes de marcarlo
+	redeemed := *token
+	redeemed.Used = false
+	redeemed.UsedAt = nil
+	return &redeemed, nil
+}
+
+func (r *MemoryRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	found := *token
+	return &found, nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.Revoked = true
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRefreshTokenRepository) RevokeAll(ctx context.Context, email string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.Email == email && !token.Revoked {
+			token.Revoked = true
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// MemoryMFAChallengeRepository implementa MFAChallengeRepository en memoria
+type MemoryMFAChallengeRepository struct {
+	mu         sync.Mutex
+	challenges map[string]memoryTokenEntry
+}
+
+// NewMemoryMFAChallengeRepository crea un repositorio en memoria vacío
+func NewMemoryMFAChallengeRepository() *MemoryMFAChallengeRepository {
+	return &MemoryMFAChallengeRepository{challenges: make(map[string]memoryTokenEntry)}
+}
+
+func (r *MemoryMFAChallengeRepository) Create(ctx context.Context, tokenHash string, email string, now time.Time, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.challenges[tokenHash] = memoryTokenEntry{email: email, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *MemoryMFAChallengeRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	challenge, ok := r.challenges[tokenHash]
+	if !ok || !challenge.expiresAt.After(now) {
+		return "", ErrTokenNotFound
+	}
+	delete(r.challenges, tokenHash)
+	return challenge.email, nil
+}
+
+func (r *MemoryMFAChallengeRepository) DeleteByEmail(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, challenge := range r.challenges {
+		if challenge.email == email {
+			delete(r.challenges, hash)
+		}
+	}
+	return nil
+}
+
+// MemoryPasswordResetRepository implementa PasswordResetRepository en memoria
+type MemoryPasswordResetRepository struct {
+	mu     sync.Mutex
+	resets map[string]*memoryTokenEntry
+}
+
+// NewMemoryPasswordResetRepository crea un repositorio en memoria vacío
+func NewMemoryPasswordResetRepository() *MemoryPasswordResetRepository {
+	return &MemoryPasswordResetRepository{resets: make(map[string]*memoryTokenEntry)}
+}
+
+func (r *MemoryPasswordResetRepository) Create(ctx context.Context, tokenHash string, email string, now time.Time, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.resets[tokenHash] = &memoryTokenEntry{email: email, expiresAt: expiresAt}
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) FindValid(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset, ok := r.resets[tokenHash]
+	if !ok || reset.used || !reset.expiresAt.After(now) {
+		return "", ErrTokenNotFound
+	}
+	return reset.email, nil
+}
+
+func (r *MemoryPasswordResetRepository) MarkUsed(ctx context.Context, tokenHash string, now time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reset, ok := r.resets[tokenHash]
+	if !ok || reset.used {
+		return false, nil
+	}
+	reset.used = true
+	return true, nil
+}
+
+func (r *MemoryPasswordResetRepository) InvalidateAll(ctx context.Context, email string, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, reset := range r.resets {
+		if reset.email == email {
+			reset.used = true
+		}
+	}
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) DeleteByEmail(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, reset := range r.resets {
+		if reset.email == email {
+			delete(r.resets, hash)
+		}
+	}
+	return nil
+}
+
+// memoryTokenEntry es un token de un solo uso guardado en memoria
+type memoryTokenEntry struct {
+	email     string
+	expiresAt time.Time
+	used      bool
+}
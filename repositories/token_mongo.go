@@ -0,0 +1,180 @@
+// ./repositories/token_mongo.go
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	_ RefreshTokenRepository  = (*MongoRefreshTokenRepository)(nil)
+	_ MFAChallengeRepository  = (*MongoMFAChallengeRepository)(nil)
+	_ PasswordResetRepository = (*MongoPasswordResetRepository)(nil)
+)
+
+// MongoRefreshTokenRepository implementa RefreshTokenRepository sobre "refresh_tokens"
+type MongoRefreshTokenRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoRefreshTokenRepository crea el repositorio sobre la colección indicada
+func NewMongoRefreshTokenRepository(collection *mongo.Collection) *MongoRefreshTokenRepository {
+	return &MongoRefreshTokenRepository{collection: collection}
+}
+
+func (r *MongoRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *MongoRefreshTokenRepository) Redeem(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{
+		"token_hash": tokenHash,
+		"used":       false,
+		"revoked":    false,
+		"expires_at": bson.M{"$gt": now},
+	}, bson.M{
+		"$set": bson.M{"used": true, "used_at": now},
+	}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *MongoRefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.collection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrTokenNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *MongoRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string, now time.Time) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"family_id": familyID}, bson.M{
+		"$set": bson.M{"revoked": true, "revoked_at": now},
+	})
+	return err
+}
+
+func (r *MongoRefreshTokenRepository) RevokeAll(ctx context.Context, email string, now time.Time) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"email": email, "revoked": false},
+		bson.M{"$set": bson.M{"revoked": true, "revoked_at": now}},
+	)
+	return err
+}
+
+// MongoMFAChallengeRepository implementa MFAChallengeRepository sobre "mfa_challenges"
+type MongoMFAChallengeRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoMFAChallengeRepository crea el repositorio sobre la colección indicada
+func NewMongoMFAChallengeRepository(collection *mongo.Collection) *MongoMFAChallengeRepository {
+	return &MongoMFAChallengeRepository{collection: collection}
+}
+
+func (r *MongoMFAChallengeRepository) Create(ctx context.Context, tokenHash string, email string, now time.Time, expiresAt time.Time) error {
+	_, err := r.collection.InsertOne(ctx, bson.M{
+		"token_hash": tokenHash,
+		"email":      email,
+		"created_at": now,
+		"expires_at": expiresAt,
+	})
+	return err
+}
+
+func (r *MongoMFAChallengeRepository) Consume(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	var doc struct {
+		Email string `bson:"email"`
+	}
+	err := r.collection.FindOneAndDelete(ctx, bson.M{
+		"token_hash": tokenHash,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return "", ErrTokenNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return doc.Email, nil
+}
+
+func (r *MongoMFAChallengeRepository) DeleteByEmail(ctx context.Context, email string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"email": email})
+	return err
+}
+
+// MongoPasswordResetRepository implementa PasswordResetRepository sobre "password_resets"
+type MongoPasswordResetRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPasswordResetRepository crea el repositorio sobre la colección indicada
+func NewMongoPasswordResetRepository(collection *mongo.Collection) *MongoPasswordResetRepository {
+	return &MongoPasswordResetRepository{collection: collection}
+}
+
+func (r *MongoPasswordResetRepository) Create(ctx context.Context, tokenHash string, email string, now time.Time, expiresAt time.Time) error {
+	_, err := r.collection.InsertOne(ctx, bson.M{
+		"token_hash": tokenHash,
+		"email":      email,
+		"used":       false,
+		"created_at": now,
+		"expires_at": expiresAt,
+	})
+	return err
+}
+
+func (r *MongoPasswordResetRepository) FindValid(ctx context.Context, tokenHash string, now time.Time) (string, error) {
+	var doc struct {
+		Email string `bson:"email"`
+	}
+	err := r.collection.FindOne(ctx, bson.M{
+		"token_hash": tokenHash,
+		"used":       false,
+		"expires_at": bson.M{"$gt": now},
+	}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return "", ErrTokenNotFound
+	} else if err != nil {
+		return "", err
+	}
+	return doc.Email, nil
+}
+
+func (r *MongoPasswordResetRepository) MarkUsed(ctx context.Context, tokenHash string, now time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"token_hash": tokenHash,
+		"used":       false,
+	}, bson.M{
+		"$set": bson.M{"used": true, "used_at": now},
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoPasswordResetRepository) InvalidateAll(ctx context.Context, email string, now time.Time) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"email": email, "used": false}, bson.M{
+		"$set": bson.M{"used": true, "used_at": now},
+	})
+	return err
+}
+
+func (r *MongoPasswordResetRepository) DeleteByEmail(ctx context.Context, email string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"email": email})
+	return err
+}
@@ -0,0 +1,48 @@
+// ./repositories/token_repository.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+)
+
+// ErrTokenNotFound se devuelve cuando no existe un token vigente con el hash indicado
+var ErrTokenNotFound = errors.New("token no encontrado o expirado")
+
+// RefreshTokenRepository encapsula el acceso a los refresh tokens.
+// Los tokens se buscan por su hash (utils.HashToken), nunca por el valor en claro.
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	// Redeem marca como usado un token vigente (sin usar, sin revocar y sin expirar) de forma
+	// atómica, para que solo una petición pueda canjearlo; devuelve ErrTokenNotFound si no hay ninguno
+	Redeem(ctx context.Context, tokenHash string, now time.Time) (*models.RefreshToken, error)
+	// FindByHash devuelve el token aunque ya no esté vigente; ErrTokenNotFound si no existe
+	FindByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID string, now time.Time) error
+	// RevokeAll revoca todos los refresh tokens del usuario
+	RevokeAll(ctx context.Context, email string, now time.Time) error
+}
+
+// MFAChallengeRepository encapsula los retos MFA que emite el login para el segundo paso
+type MFAChallengeRepository interface {
+	Create(ctx context.Context, tokenHash string, email string, now time.Time, expiresAt time.Time) error
+	// Consume elimina un reto vigente de forma atómica y devuelve su email;
+	// ErrTokenNotFound si no existe, venció o ya se consumió
+	Consume(ctx context.Context, tokenHash string, now time.Time) (string, error)
+	DeleteByEmail(ctx context.Context, email string) error
+}
+
+// PasswordResetRepository encapsula los tokens de restablecimiento de contraseña
+type PasswordResetRepository interface {
+	Create(ctx context.Context, tokenHash string, email string, now time.Time, expiresAt time.Time) error
+	// FindValid devuelve el email de un token sin usar y sin expirar; ErrTokenNotFound si no hay
+	FindValid(ctx context.Context, tokenHash string, now time.Time) (string, error)
+	// MarkUsed marca el token como usado solo si no lo estaba; indica si lo marcó
+	MarkUsed(ctx context.Context, tokenHash string, now time.Time) (bool, error)
+	// InvalidateAll marca como usados todos los tokens pendientes del usuario
+	InvalidateAll(ctx context.Context, email string, now time.Time) error
+	DeleteByEmail(ctx context.Context, email string) error
+}
@@ -0,0 +1,379 @@
+// ./repositories/user_memory.go
+package repositories
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var _ UserRepository = (*MemoryUserRepository)(nil)
+
+// MemoryUserRepository implementa UserRepository en memoria (pruebas y desarrollo sin MongoDB)
+type MemoryUserRepository struct {
+	mu sync.RWMutex
+	// Indexado por el email en minúsculas, igual que la comparación de MongoDB
+	users map[string]*models.User
+}
+
+// NewMemoryUserRepository crea un repositorio en memoria vacío
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[string]*models.User)}
+}
+
+func (r *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[emailKey(email)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return cloneUser(user), nil
+}
+
+func (r *MemoryUserRepository) FindByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if strings.Contains(identifier, "@") {
+		if user, ok := r.users[emailKey(identifier)]; ok {
+			return cloneUser(user), nil
+		}
+		return nil, ErrNotFound
+	}
+	for _, user := range r.users {
+		if strings.EqualFold(user.Username, identifier) {
+			return cloneUser(user), nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *MemoryUserRepository) UsernameTaken(ctx context.Context, username string, exceptEmail string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.usernameTaken(username, exceptEmail), nil
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[emailKey(user.Email)]; ok || r.usernameTaken(user.Username, "") {
+		return ErrDuplicate
+	}
+
+	stored := cloneUser(user)
+	if stored.ID.IsZero() {
+		stored.ID = primitive.NewObjectID()
+	}
+	r.users[emailKey(stored.Email)] = stored
+	return nil
+}
+
+func (r *MemoryUserRepository) Delete(ctx context.Context, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[emailKey(email)]; !ok {
+		return ErrNotFound
+	}
+	delete(r.users, emailKey(email))
+	return nil
+}
+
+func (r *MemoryUserRepository) List(ctx context.Context, filter UserFilter, skip int64, limit int64) ([]models.User, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := []models.User{}
+	for _, user := range r.users {
+		if matchesUserFilter(user, filter) {
+			matched = append(matched, *cloneUser(user))
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].DateRegister.After(matched[j].DateRegister)
+	})
+
+	total := int64(len(matched))
+	if skip >= total {
+		return []models.User{}, total, nil
+	}
+	end := total
+	if limit > 0 && skip+limit < total {
+		end = skip + limit
+	}
+	return matched[skip:end], total, nil
+}
+
+func (r *MemoryUserRepository) DeleteUnverifiedBefore(ctx context.Context, t time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, user := range r.users {
+		if !user.IsEmailVerified() && user.DateRegister.Before(t) {
+			delete(r.users, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *MemoryUserRepository) UpdateLastLogin(ctx context.Context, email string, t time.Time) error {
+	return r.update(email, func(u *models.User) { u.LastLogin = &t })
+}
+
+func (r *MemoryUserRepository) UpdatePasswordHash(ctx context.Context, email string, hash string) error {
+	return r.update(email, func(u *models.User) { u.Password = hash })
+}
+
+func (r *MemoryUserRepository) SetPassword(ctx context.Context, email string, hash string, changedAt time.Time) error {
+	return r.update(email, func(u *models.User) {
+		u.Password = hash
+		u.PasswordChangedAt = &changedAt
+		u.LoginFailedAttempts = 0
+		u.LoginLockedUntil = nil
+		u.PasswordResetRequired = false
+	})
+}
+
+func (r *MemoryUserRepository) SetUsername(ctx context.Context, email string, username string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[emailKey(email)]
+	if !ok {
+		return ErrNotFound
+	}
+	if r.usernameTaken(username, email) {
+		return ErrDuplicate
+	}
+	user.Username = username
+	return nil
+}
+
+func (r *MemoryUserRepository) SetRole(ctx context.Context, email string, role string) error {
+	return r.update(email, func(u *models.User) { u.Role = role })
+}
+
+func (r *MemoryUserRepository) SetDisabled(ctx context.Context, email string, disabled bool) error {
+	return r.update(email, func(u *models.User) {
+		u.Disabled = disabled
+		u.DisabledAt = nil
+		if disabled {
+			now := time.Now()
+			u.DisabledAt = &now
+		}
+	})
+}
+
+func (r *MemoryUserRepository) SetPasswordResetRequired(ctx context.Context, email string) error {
+	return r.update(email, func(u *models.User) { u.PasswordResetRequired = true })
+}
+
+func (r *MemoryUserRepository) MarkEmailVerified(ctx context.Context, email string, t time.Time) error {
+	return r.update(email, func(u *models.User) {
+		verified := true
+		u.EmailVerified = &verified
+		u.EmailVerifiedAt = &t
+		u.VerificationSentAt = nil
+	})
+}
+
+func (r *MemoryUserRepository) ReserveVerificationEmail(ctx context.Context, email string, now time.Time, interval time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[emailKey(email)]
+	if !ok || user.EmailVerified == nil || *user.EmailVerified {
+		return false, nil
+	}
+	if user.VerificationSentAt != nil && user.VerificationSentAt.After(now.Add(-interval)) {
+		return false, nil
+	}
+	user.VerificationSentAt = &now
+	return true, nil
+}
+
+func (r *MemoryUserRepository) SetPendingMFASecret(ctx context.Context, email string, secret string) error {
+	return r.update(email, func(u *models.User) { u.MFAPendingSecret = secret })
+}
+
+func (r *MemoryUserRepository) EnableMFA(ctx context.Context, email string, secret string, recoveryCodeHashes []string, step int64) error {
+	return r.update(email, func(u *models.User) {
+		u.MFASecret = secret
+		u.MFAEnabled = true
+		u.MFARecoveryCodes = append([]string(nil), recoveryCodeHashes...)
+		u.MFALastStep = step
+		u.MFAPendingSecret = ""
+	})
+}
+
+func (r *MemoryUserRepository) DisableMFA(ctx context.Context, email string) error {
+	return r.update(email, func(u *models.User) {
+		u.MFAEnabled = false
+		u.MFASecret = ""
+		u.MFAPendingSecret = ""
+		u.MFARecoveryCodes = nil
+		u.MFALastStep = 0
+		u.OTPFailedAttempts = 0
+		u.OTPLockedUntil = nil
+	})
+}
+
+func (r *MemoryUserRepository) SetRecoveryCodes(ctx context.Context, email string, hashes []string) error {
+	return r.update(email, func(u *models.User) { u.MFARecoveryCodes = append([]string(nil), hashes...) })
+}
+
+func (r *MemoryUserRepository) ConsumeRecoveryCode(ctx context.Context, email string, hash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[emailKey(email)]
+	if !ok {
+		return false, nil
+	}
+	for i, stored := range user.MFARecoveryCodes {
+		if stored == hash {
+			user.MFARecoveryCodes = append(user.MFARecoveryCodes[:i:i], user.MFARecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryUserRepository) AdvanceTOTPStep(ctx context.Context, email string, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[emailKey(email)]
+	if !ok || user.MFALastStep >= step {
+		return false, nil
+	}
+	user.MFALastStep = step
+	return true, nil
+}
+
+func (r *MemoryUserRepository) IncrementLoginFailures(ctx context.Context, email string) (int, error) {
+	var attempts int
+	err := r.update(email, func(u *models.User) {
+		u.LoginFailedAttempts++
+		attempts = u.LoginFailedAttempts
+	})
+	return attempts, err
+}
+
+func (r *MemoryUserRepository) SetLoginLockedUntil(ctx context.Context, email string, t time.Time) error {
+	return r.update(email, func(u *models.User) { u.LoginLockedUntil = &t })
+}
+
+func (r *MemoryUserRepository) ResetLoginFailures(ctx context.Context, email string) error {
+	return r.update(email, func(u *models.User) {
+		u.LoginFailedAttempts = 0
+		u.LoginLockedUntil = nil
+	})
+}
+
+func (r *MemoryUserRepository) IncrementOTPFailures(ctx context.Context, email string) (int, error) {
+	var attempts int
+	err := r.update(email, func(u *models.User) {
+		u.OTPFailedAttempts++
+		attempts = u.OTPFailedAttempts
+	})
+	return attempts, err
+}
+
+func (r *MemoryUserRepository) SetOTPLockedUntil(ctx context.Context, email string, t time.Time) error {
+	return r.update(email, func(u *models.User) { u.OTPLockedUntil = &t })
+}
+
+func (r *MemoryUserRepository) ResetOTPFailures(ctx context.Context, email string) error {
+	return r.update(email, func(u *models.User) {
+		u.OTPFailedAttempts = 0
+		u.OTPLockedUntil = nil
+	})
+}
+
+func (r *MemoryUserRepository) Unlock(ctx context.Context, email string) error {
+	return r.update(email, func(u *models.User) {
+		u.LoginFailedAttempts = 0
+		u.LoginLockedUntil = nil
+		u.OTPFailedAttempts = 0
+		u.OTPLockedUntil = nil
+	})
+}
+
+// update aplica fn al usuario guardado bajo el candado de escritura
+func (r *MemoryUserRepository) update(email string, fn func(u *models.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[emailKey(email)]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(user)
+	return nil
+}
+
+// usernameTaken se llama con el candado tomado
+func (r *MemoryUserRepository) usernameTaken(username string, exceptEmail string) bool {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Username, username) && !strings.EqualFold(user.Email, exceptEmail) {
+			return true
+		}
+	}
+	return false
+}
+
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+// cloneUser copia el usuario para que quien lo reciba no modifique el almacenado
+func cloneUser(user *models.User) *models.User {
+	clone := *user
+	clone.MFARecoveryCodes = append([]string(nil), user.MFARecoveryCodes...)
+	return &clone
+}
+
+func matchesUserFilter(user *models.User, filter UserFilter) bool {
+	if !inDateRange(&user.DateRegister, filter.RegisteredFrom, filter.RegisteredTo) {
+		return false
+	}
+	if (filter.LastLoginFrom != nil || filter.LastLoginTo != nil) && !inDateRange(user.LastLogin, filter.LastLoginFrom, filter.LastLoginTo) {
+		return false
+	}
+	if filter.MFAEnabled != nil && user.MFAEnabled != *filter.MFAEnabled {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(user.Email), search) && !strings.Contains(strings.ToLower(user.Username), search) {
+			return false
+		}
+	}
+	return true
+}
+
+func inDateRange(t *time.Time, from *time.Time, to *time.Time) bool {
+	if t == nil {
+		return from == nil && to == nil
+	}
+	if from != nil && t.Before(*from) {
+		return false
+	}
+	if to != nil && t.After(*to) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,366 @@
+// ./repositories/user_mongo.go
+package repositories
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/Ana-Gabs/actividadr-back/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ UserRepository = (*MongoUserRepository)(nil)
+
+// MongoUserRepository implementa UserRepository sobre la colección "users".
+// Las consultas por email o nombre de usuario usan config.UserCollation, la misma de los índices únicos.
+type MongoUserRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoUserRepository crea el repositorio sobre la colección indicada
+func NewMongoUserRepository(collection *mongo.Collection) *MongoUserRepository {
+	return &MongoUserRepository{collection: collection}
+}
+
+func (r *MongoUserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *MongoUserRepository) FindByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error) {
+	if strings.Contains(identifier, "@") {
+		return r.findOne(ctx, bson.M{"email": identifier})
+	}
+	return r.findOne(ctx, bson.M{"username": identifier})
+}
+
+func (r *MongoUserRepository) UsernameTaken(ctx context.Context, username string, exceptEmail string) (bool, error) {
+	err := r.collection.FindOne(ctx, bson.M{
+		"username": username,
+		"email":    bson.M{"$ne": exceptEmail},
+	}, options.FindOne().SetCollation(config.UserCollation)).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *MongoUserRepository) Create(ctx context.Context, user *models.User) error {
+	_, err := r.collection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *MongoUserRepository) Delete(ctx context.Context, email string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"email": email}, options.Delete().SetCollation(config.UserCollation))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoUserRepository) List(ctx context.Context, filter UserFilter, skip int64, limit int64) ([]models.User, int64, error) {
+	query := mongoUserFilter(filter)
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := r.collection.Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "date_register", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func (r *MongoUserRepository) DeleteUnverifiedBefore(ctx context.Context, t time.Time) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, bson.M{
+		"email_verified": false,
+		"date_register":  bson.M{"$lt": t},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *MongoUserRepository) UpdateLastLogin(ctx context.Context, email string, t time.Time) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"last_login": t}})
+}
+
+func (r *MongoUserRepository) UpdatePasswordHash(ctx context.Context, email string, hash string) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"password": hash}})
+}
+
+func (r *MongoUserRepository) SetPassword(ctx context.Context, email string, hash string, changedAt time.Time) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set": bson.M{
+			"password":              hash,
+			"password_changed_at":   changedAt,
+			"login_failed_attempts": 0,
+		},
+		"$unset": bson.M{"login_locked_until": "", "password_reset_required": ""},
+	})
+}
+
+func (r *MongoUserRepository) SetUsername(ctx context.Context, email string, username string) error {
+	err := r.updateOne(ctx, email, bson.M{"$set": bson.M{"username": username}})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *MongoUserRepository) SetRole(ctx context.Context, email string, role string) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"role": role}})
+}
+
+func (r *MongoUserRepository) SetDisabled(ctx context.Context, email string, disabled bool) error {
+	if disabled {
+		return r.updateOne(ctx, email, bson.M{"$set": bson.M{"disabled": true, "disabled_at": time.Now()}})
+	}
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"disabled": false}, "$unset": bson.M{"disabled_at": ""}})
+}
+
+func (r *MongoUserRepository) SetPasswordResetRequired(ctx context.Context, email string) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"password_reset_required": true}})
+}
+
+func (r *MongoUserRepository) MarkEmailVerified(ctx context.Context, email string, t time.Time) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set":   bson.M{"email_verified": true, "email_verified_at": t},
+		"$unset": bson.M{"verification_sent_at": ""},
+	})
+}
+
+func (r *MongoUserRepository) ReserveVerificationEmail(ctx context.Context, email string, now time.Time, interval time.Duration) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"email":          email,
+		"email_verified": false,
+		"$or": []bson.M{
+			{"verification_sent_at": bson.M{"$lte": now.Add(-interval)}},
+			{"verification_sent_at": bson.M{"$exists": false}},
+		},
+	}, bson.M{
+		"$set": bson.M{"verification_sent_at": now},
+	}, options.Update().SetCollation(config.UserCollation))
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoUserRepository) SetPendingMFASecret(ctx context.Context, email string, secret string) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"mfa_pending_secret": secret}})
+}
+
+func (r *MongoUserRepository) EnableMFA(ctx context.Context, email string, secret string, recoveryCodeHashes []string, step int64) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set": bson.M{
+			"mfa_secret":         secret,
+			"mfaEnabled":         true,
+			"mfa_recovery_codes": recoveryCodeHashes,
+			"mfa_last_step":      step,
+		},
+		"$unset": bson.M{"mfa_pending_secret": ""},
+	})
+}
+
+func (r *MongoUserRepository) DisableMFA(ctx context.Context, email string) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set": bson.M{"mfaEnabled": false, "otp_failed_attempts": 0},
+		"$unset": bson.M{
+			"mfa_secret":         "",
+			"mfa_pending_secret": "",
+			"mfa_recovery_codes": "",
+			"mfa_last_step":      "",
+			"otp_locked_until":   "",
+		},
+	})
+}
+
+func (r *MongoUserRepository) SetRecoveryCodes(ctx context.Context, email string, hashes []string) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"mfa_recovery_codes": hashes}})
+}
+
+func (r *MongoUserRepository) ConsumeRecoveryCode(ctx context.Context, email string, hash string) (bool, error) {
+	// $pull condicionado: si otra petición ya lo usó, no se modifica nada.
+	// Sin collation: los hashes deben compararse exactamente.
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"email": email, "mfa_recovery_codes": hash},
+		bson.M{"$pull": bson.M{"mfa_recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoUserRepository) AdvanceTOTPStep(ctx context.Context, email string, step int64) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx, bson.M{
+		"email": email,
+		"$or": []bson.M{
+			{"mfa_last_step": bson.M{"$lt": step}},
+			{"mfa_last_step": bson.M{"$exists": false}},
+		},
+	}, bson.M{
+		"$set": bson.M{"mfa_last_step": step},
+	}, options.Update().SetCollation(config.UserCollation))
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoUserRepository) IncrementLoginFailures(ctx context.Context, email string) (int, error) {
+	return r.increment(ctx, email, "login_failed_attempts")
+}
+
+func (r *MongoUserRepository) SetLoginLockedUntil(ctx context.Context, email string, t time.Time) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"login_locked_until": t}})
+}
+
+func (r *MongoUserRepository) ResetLoginFailures(ctx context.Context, email string) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set":   bson.M{"login_failed_attempts": 0},
+		"$unset": bson.M{"login_locked_until": ""},
+	})
+}
+
+func (r *MongoUserRepository) IncrementOTPFailures(ctx context.Context, email string) (int, error) {
+	return r.increment(ctx, email, "otp_failed_attempts")
+}
+
+func (r *MongoUserRepository) SetOTPLockedUntil(ctx context.Context, email string, t time.Time) error {
+	return r.updateOne(ctx, email, bson.M{"$set": bson.M{"otp_locked_until": t}})
+}
+
+func (r *MongoUserRepository) ResetOTPFailures(ctx context.Context, email string) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set":   bson.M{"otp_failed_attempts": 0},
+		"$unset": bson.M{"otp_locked_until": ""},
+	})
+}
+
+func (r *MongoUserRepository) Unlock(ctx context.Context, email string) error {
+	return r.updateOne(ctx, email, bson.M{
+		"$set": bson.M{
+			"login_failed_attempts": 0,
+			"otp_failed_attempts":   0,
+		},
+		"$unset": bson.M{
+			"login_locked_until": "",
+			"otp_locked_until":   "",
+		},
+	})
+}
+
+func (r *MongoUserRepository) findOne(ctx context.Context, filter bson.M) (*models.User, error) {
+	var user models.User
+	err := r.collection.FindOne(ctx, filter, options.FindOne().SetCollation(config.UserCollation)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *MongoUserRepository) updateOne(ctx context.Context, email string, update bson.M) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"email": email}, update, options.Update().SetCollation(config.UserCollation))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *MongoUserRepository) increment(ctx context.Context, email string, field string) (int, error) {
+	var updated bson.M
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"email": email},
+		bson.M{"$inc": bson.M{field: 1}},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{field: 1}).
+			SetCollation(config.UserCollation),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrNotFound
+	} else if err != nil {
+		return 0, err
+	}
+
+	switch n := updated[field].(type) {
+	case int32:
+		return int(n), nil
+	case int64:
+		return int(n), nil
+	case float64:
+		return int(n), nil
+	}
+	return 0, nil
+}
+
+// mongoUserFilter traduce UserFilter a una consulta de MongoDB
+func mongoUserFilter(filter UserFilter) bson.M {
+	query := bson.M{}
+
+	if dateRange := mongoDateRange(filter.RegisteredFrom, filter.RegisteredTo); dateRange != nil {
+		query["date_register"] = dateRange
+	}
+	if dateRange := mongoDateRange(filter.LastLoginFrom, filter.LastLoginTo); dateRange != nil {
+		query["last_login"] = dateRange
+	}
+
+	if filter.MFAEnabled != nil {
+		if *filter.MFAEnabled {
+			query["mfaEnabled"] = true
+		} else {
+			query["mfaEnabled"] = bson.M{"$ne": true}
+		}
+	}
+
+	if filter.Search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(filter.Search), "$options": "i"}
+		query["$or"] = []bson.M{{"email": pattern}, {"username": pattern}}
+	}
+	return query
+}
+
+func mongoDateRange(from *time.Time, to *time.Time) bson.M {
+	dateRange := bson.M{}
+	if from != nil {
+		dateRange["$gte"] = *from
+	}
+	if to != nil {
+		dateRange["$lte"] = *to
+	}
+	if len(dateRange) == 0 {
+		return nil
+	}
+	return dateRange
+}
@@ -0,0 +1,83 @@
+// ./repositories/user_repository.go
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Ana-Gabs/actividadr-back/models"
+)
+
+// ErrNotFound se devuelve cuando no existe un usuario con el criterio indicado
+var ErrNotFound = errors.New("usuario no encontrado")
+
+// ErrDuplicate se devuelve cuando el email o el nombre de usuario ya pertenecen a otra cuenta
+// (la comparación no distingue mayúsculas)
+var ErrDuplicate = errors.New("el email o el nombre de usuario ya están registrados")
+
+// UserFilter son los filtros opcionales del listado de usuarios
+type UserFilter struct {
+	RegisteredFrom *time.Time
+	RegisteredTo   *time.Time
+	LastLoginFrom  *time.Time
+	LastLoginTo    *time.Time
+	MFAEnabled     *bool
+	// Search busca, sin distinguir mayúsculas, dentro del email o del nombre de usuario
+	Search string
+}
+
+// UserRepository encapsula el acceso a los usuarios.
+// Emails y nombres de usuario se comparan sin distinguir mayúsculas.
+// Los métodos que modifican un usuario devuelven ErrNotFound si el email no existe.
+type UserRepository interface {
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	// FindByEmailOrUsername busca por email si identifier contiene "@" y por nombre de
+	// usuario si no, para que un nombre no pueda coincidir con el email de otra cuenta
+	FindByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error)
+	// UsernameTaken indica si otro usuario (distinto de exceptEmail) ya usa el nombre
+	UsernameTaken(ctx context.Context, username string, exceptEmail string) (bool, error)
+	// Create devuelve ErrDuplicate si el email o el nombre de usuario ya existen
+	Create(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, email string) error
+	List(ctx context.Context, filter UserFilter, skip int64, limit int64) ([]models.User, int64, error)
+	// DeleteUnverifiedBefore elimina las cuentas sin verificar registradas antes de t
+	DeleteUnverifiedBefore(ctx context.Context, t time.Time) (int64, error)
+
+	UpdateLastLogin(ctx context.Context, email string, t time.Time) error
+	// UpdatePasswordHash reemplaza el hash sin otros efectos (migración de parámetros)
+	UpdatePasswordHash(ctx context.Context, email string, hash string) error
+	// SetPassword cambia la contraseña y limpia el bloqueo de login y el restablecimiento obligatorio
+	SetPassword(ctx context.Context, email string, hash string, changedAt time.Time) error
+	// SetUsername devuelve ErrDuplicate si otra cuenta ya usa el nombre
+	SetUsername(ctx context.Context, email string, username string) error
+	SetRole(ctx context.Context, email string, role string) error
+	SetDisabled(ctx context.Context, email string, disabled bool) error
+	SetPasswordResetRequired(ctx context.Context, email string) error
+	MarkEmailVerified(ctx context.Context, email string, t time.Time) error
+	// ReserveVerificationEmail marca un nuevo envío de verificación solo si la cuenta no está
+	// verificada y el último envío fue hace más de interval; indica si se reservó
+	ReserveVerificationEmail(ctx context.Context, email string, now time.Time, interval time.Duration) (bool, error)
+
+	SetPendingMFASecret(ctx context.Context, email string, secret string) error
+	// EnableMFA activa el secreto confirmado y guarda los códigos de recuperación y el paso TOTP usado
+	EnableMFA(ctx context.Context, email string, secret string, recoveryCodeHashes []string, step int64) error
+	// DisableMFA elimina el secreto, los códigos de recuperación y el bloqueo OTP
+	DisableMFA(ctx context.Context, email string) error
+	SetRecoveryCodes(ctx context.Context, email string, hashes []string) error
+	// ConsumeRecoveryCode elimina el hash si sigue presente; indica si lo eliminó
+	ConsumeRecoveryCode(ctx context.Context, email string, hash string) (bool, error)
+	// AdvanceTOTPStep guarda el paso TOTP solo si es posterior al último aceptado
+	AdvanceTOTPStep(ctx context.Context, email string, step int64) (bool, error)
+
+	// IncrementLoginFailures suma un fallo de login y devuelve el total acumulado
+	IncrementLoginFailures(ctx context.Context, email string) (int, error)
+	SetLoginLockedUntil(ctx context.Context, email string, t time.Time) error
+	ResetLoginFailures(ctx context.Context, email string) error
+	// IncrementOTPFailures suma un código OTP fallido y devuelve el total acumulado
+	IncrementOTPFailures(ctx context.Context, email string) (int, error)
+	SetOTPLockedUntil(ctx context.Context, email string, t time.Time) error
+	ResetOTPFailures(ctx context.Context, email string) error
+	// Unlock limpia los bloqueos de login y de OTP
+	Unlock(ctx context.Context, email string) error
+}
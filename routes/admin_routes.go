@@ -0,0 +1,33 @@
+// ./routes/admin_routes.go
+
+package routes
+
+import (
+	"github.com/Ana-Gabs/actividadr-back/controllers"
+	"github.com/Ana-Gabs/actividadr-back/middlewares"
+	"github.com/Ana-Gabs/actividadr-back/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAdminRoutes(app *fiber.App, h *controllers.Handler, auth fiber.Handler) {
+	// Todas las rutas de administración requieren rol admin
+	admin := app.Group("/admin", auth, middlewares.RequireRole(utils.RoleAdmin))
+
+	// Gestión de usuarios
+	admin.Get("/users", h.ListUsers)
+	admin.Get("/users/:email", h.GetUser)
+	admin.Post("/users/:email/disable", h.DisableUser)
+	admin.Post("/users/:email/enable", h.EnableUser)
+	admin.Post("/users/:email/reset-mfa", h.ResetUserMFA)
+	admin.Post("/users/:email/reset-password", h.ForceUserPasswordReset)
+
+	// Gestión de roles
+	admin.Put("/users/:email/role", h.SetUserRole)
+	admin.Delete("/users/:email/role", h.RemoveUserRole)
+
+	// Bloqueos por intentos fallidos
+	admin.Post("/users/:email/unlock", h.UnlockUser)
+
+	// Estado detallado de la instancia
+	admin.Get("/health", h.GetHealthDetails)
+}
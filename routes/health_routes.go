@@ -0,0 +1,13 @@
+// ./routes/health_routes.go
+package routes
+
+import (
+	"github.com/Ana-Gabs/actividadr-back/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupHealthRoutes(app *fiber.App, h *controllers.Handler) {
+	// Sondas del orquestador: públicas y sin registro en la colección de logs
+	app.Get("/healthz", h.Healthz)
+	app.Get("/readyz", h.Readyz)
+}
@@ -3,18 +3,23 @@ package routes
 
 import (
 	"github.com/Ana-Gabs/actividadr-back/controllers"
+	"github.com/Ana-Gabs/actividadr-back/middlewares"
+	"github.com/Ana-Gabs/actividadr-back/utils"
 	"github.com/gofiber/fiber/v2"
-	//"github.com/Ana-Gabs/actividadr-back/middlewares"
 )
 
-func SetupLogsRoutes(app *fiber.App) {
+func SetupLogsRoutes(app *fiber.App, h *controllers.Handler, auth fiber.Handler) {
+	// Las analíticas de logs solo están disponibles para administradores y auditores
+	logs := app.Group("/logs", auth, middlewares.RequireRole(utils.RoleAdmin, utils.RoleAuditor))
+
 	// Rutas para obtener logs
-	app.Get("/logs/level", controllers.GetLogsByLevel)
-	app.Get("/logs/time", controllers.GetLogsByResponseTime)
-	app.Get("/logs/status", controllers.GetLogsByStatus)
+	logs.Get("/level", h.GetLogsByLevel)
+	logs.Get("/time", h.GetLogsByResponseTime)
+	logs.Get("/status", h.GetLogsByStatus)
+	logs.Get("/timeseries", h.GetLogsTimeseries)
 
 	// Rutas con rate limiting (descomentar para habilitar)
-	// app.Get("/logs/level", middlewares.RateLimitMiddleware(), controllers.GetLogsByLevel)
-	// app.Get("/logs/time", middlewares.RateLimitMiddleware(), controllers.GetLogsByResponseTime)
-	// app.Get("/logs/status", middlewares.RateLimitMiddleware(), controllers.GetLogsByStatus)
+	// logs.Get("/level", middlewares.RateLimitMiddleware(), h.GetLogsByLevel)
+	// logs.Get("/time", middlewares.RateLimitMiddleware(), h.GetLogsByResponseTime)
+	// logs.Get("/status", middlewares.RateLimitMiddleware(), h.GetLogsByStatus)
 }
@@ -0,0 +1,20 @@
+// ./routes/metrics_routes.go
+package routes
+
+import (
+	"log"
+
+	"github.com/Ana-Gabs/actividadr-back/metrics"
+	"github.com/Ana-Gabs/actividadr-back/middlewares"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupMetricsRoutes(app *fiber.App, m *metrics.Metrics, token string) {
+	// Sin METRICS_TOKEN no se exponen las métricas
+	if token == "" {
+		log.Println("METRICS_TOKEN no está configurado: /metrics queda deshabilitado")
+		return
+	}
+	// Formato de texto de Prometheus; el scraper envía METRICS_TOKEN como token Bearer
+	app.Get("/metrics", middlewares.StaticTokenMiddleware(token), m.Handler())
+}
@@ -3,18 +3,44 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/Ana-Gabs/actividadr-back/controllers"
 	"github.com/Ana-Gabs/actividadr-back/middlewares"
 	"github.com/gofiber/fiber/v2"
 )
 
 
-func SetupUserRoutes(app *fiber.App) {
+func SetupUserRoutes(app *fiber.App, h *controllers.Handler, auth fiber.Handler) {
 	// Rutas de autenticación
-	app.Post("/login", middlewares.RateLimitMiddleware(), controllers.Login)
-	app.Post("/register", middlewares.RateLimitMiddleware(), controllers.Register)
-	app.Get("/info", middlewares.RateLimitMiddleware(), controllers.GetInfo)
-	// app.Get("/info", middlewares.AuthMiddleware(), controllers.GetInfo) // Comentado como en el original
-	//app.Post("/verify-otp", middlewares.RateLimitMiddleware(), controllers.VerifyOtp)
-	app.Post("/verify-otp", controllers.VerifyOtp)
+	app.Post("/login", middlewares.RateLimitMiddleware(), h.Login)
+	app.Post("/register", middlewares.RateLimitMiddleware(), h.Register)
+	app.Get("/info", middlewares.RateLimitMiddleware(), h.GetInfo)
+	// app.Get("/info", middlewares.AuthMiddleware(), h.GetInfo) // Comentado como en el original
+	app.Post("/verify-otp", middlewares.StrictRateLimitMiddleware(10, 15*time.Minute), h.VerifyOtp)
+	app.Post("/token/refresh", middlewares.RateLimitMiddleware(), h.RefreshToken)
+
+	// Cierre de sesión
+	app.Post("/logout", auth, h.Logout)
+	app.Post("/logout-all", auth, h.LogoutAll)
+
+	// Perfil del usuario autenticado
+	app.Get("/me", auth, h.GetMe)
+	app.Patch("/me", middlewares.StrictRateLimitMiddleware(10, 15*time.Minute), auth, h.UpdateMe)
+	app.Delete("/me", middlewares.StrictRateLimitMiddleware(10, 15*time.Minute), auth, h.DeleteMe)
+
+	// Verificación de email
+	app.Get("/verify-email", middlewares.RateLimitMiddleware(), h.VerifyEmail)
+	app.Post("/verify-email/resend", middlewares.StrictRateLimitMiddleware(5, 15*time.Minute), h.ResendVerificationEmail)
+
+	// Restablecimiento de contraseña
+	app.Post("/password/forgot", middlewares.RateLimitMiddleware(), h.ForgotPassword)
+	app.Post("/password/reset", middlewares.RateLimitMiddleware(), h.ResetPassword)
+
+	// Enrolamiento MFA (TOTP)
+	app.Post("/mfa/enroll", auth, h.StartMFAEnrollment)
+	app.Post("/mfa/confirm", auth, h.ConfirmMFAEnrollment)
+	app.Post("/mfa/disable", middlewares.StrictRateLimitMiddleware(10, 15*time.Minute), auth, h.DisableMFA)
+	app.Get("/mfa/recovery-codes", auth, h.GetRecoveryCodesStatus)
+	app.Post("/mfa/recovery-codes", auth, h.RegenerateRecoveryCodes)
 }
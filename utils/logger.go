@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"log"
 	"os"
 	"runtime"
@@ -8,54 +9,119 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/Ana-Gabs/actividadr-back/config"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// LogAction registra una acción HTTP en la colección "logs"
-func LogAction(email string, action string, logLevel string) fiber.Handler {
+// ActionLogger registra las acciones HTTP en la colección "logs" y en el logger de archivos
+type ActionLogger struct {
+	collection  *mongo.Collection
+	logger      *logrus.Logger
+	environment string
+}
+
+// NewActionLogger crea el registro de acciones; collection puede ser nil si no se guardan
+// en MongoDB (pruebas) y logger si no se escriben archivos
+func NewActionLogger(collection *mongo.Collection, logger *logrus.Logger, environment string) *ActionLogger {
+	return &ActionLogger{collection: collection, logger: logger, environment: environment}
+}
+
+// Clave de c.Locals con las acciones registradas durante la petición (ver Middleware)
+const pendingActionsLocal = "utils.pendingActions"
+
+type pendingAction struct {
+	email    string
+	action   string
+	logLevel string
+}
+
+// Middleware guarda las acciones registradas con LogAction cuando el handler termina, para
+// que cada registro lleve el código de estado definitivo y la duración de la petición:
+// los handlers llaman a LogAction antes de fijar el estado con c.Status
+func (l *ActionLogger) Middleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
-		err := c.Next()
-
-		duration := time.Since(start)
+		pending := &[]pendingAction{}
+		c.Locals(pendingActionsLocal, pending)
 
-		hostname, _ := os.Hostname()
+		err := c.Next()
 
 		status := c.Response().StatusCode()
-		if logLevel == "" {
-			if status >= 400 {
-				logLevel = "error"
+		if err != nil {
+			// El ErrorHandler de Fiber aún no ha escrito el error en la respuesta
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
 			} else {
-				logLevel = "info"
+				status = fiber.StatusInternalServerError
 			}
 		}
+		for _, entry := range *pending {
+			l.write(c, entry, status, time.Since(start))
+		}
+		return err
+	}
+}
+
+// LogAction registra una acción HTTP en la colección "logs". Con Middleware instalado el
+// registro se guarda al terminar la petición; sin él, de inmediato con el estado actual.
+func (l *ActionLogger) LogAction(email string, action string, logLevel string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		entry := pendingAction{email: email, action: action, logLevel: logLevel}
+		if pending, ok := c.Locals(pendingActionsLocal).(*[]pendingAction); ok {
+			*pending = append(*pending, entry)
+			return nil
+		}
+		l.write(c, entry, c.Response().StatusCode(), 0)
+		return nil
+	}
+}
+
+// write guarda una acción en "logs" y en los archivos de log
+func (l *ActionLogger) write(c *fiber.Ctx, entry pendingAction, status int, duration time.Duration) {
+	hostname, _ := os.Hostname()
 
-		logEntry := map[string]interface{}{
-			"email":         email,
-			"action":        action,
-			"logLevel":      logLevel,
-			"timestamp":     time.Now(),
-			"ip":            c.IP(),
-			"userAgent":     c.Get("User-Agent", "Unknown"),
-			"referer":       c.Get("Referer", "Unknown"),
-			"origin":        c.Get("Origin", "Unknown"),
-			"method":        c.Method(),
-			"url":           c.OriginalURL(),
-			"status":        status,
-			"responseTime":  duration.Milliseconds(),
-			"protocol":      c.Protocol(),
-			"hostname":      hostname,
-			"environment":   os.Getenv("NODE_ENV"),
-			"goVersion":     strings.TrimPrefix(runtime.Version(), "go"),
-			"pid":           os.Getpid(),
+	logLevel := entry.logLevel
+	if logLevel == "" {
+		if status >= 400 {
+			logLevel = "error"
+		} else {
+			logLevel = "info"
 		}
+	}
+
+	logEntry := map[string]interface{}{
+		"email":        entry.email,
+		"action":       entry.action,
+		"logLevel":     logLevel,
+		"timestamp":    time.Now(),
+		"ip":           c.IP(),
+		"userAgent":    c.Get("User-Agent", "Unknown"),
+		"referer":      c.Get("Referer", "Unknown"),
+		"origin":       c.Get("Origin", "Unknown"),
+		"method":       c.Method(),
+		"url":          c.OriginalURL(),
+		"status":       status,
+		"responseTime": duration.Milliseconds(),
+		"protocol":     c.Protocol(),
+		"hostname":     hostname,
+		"environment":  l.environment,
+		"goVersion":    strings.TrimPrefix(runtime.Version(), "go"),
+		"pid":          os.Getpid(),
+	}
 
-		collection := config.GetCollection("logs")
-		_, insertErr := collection.InsertOne(c.Context(), logEntry)
+	if l.collection != nil {
+		_, insertErr := l.collection.InsertOne(c.Context(), logEntry)
 		if insertErr != nil {
 			log.Println("Error al registrar log:", insertErr)
 		}
+	}
 
-		return err
+	if l.logger != nil {
+		level, parseErr := logrus.ParseLevel(logLevel)
+		if parseErr != nil {
+			level = logrus.InfoLevel
+		}
+		l.logger.WithFields(logrus.Fields(logEntry)).Log(level, entry.action)
 	}
 }
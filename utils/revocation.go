@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Tiempo que una consulta a "revoked_tokens" se reutiliza antes de volver a MongoDB.
+// Las revocaciones hechas en esta misma instancia se aplican de inmediato.
+const revocationCacheTTL = 30 * time.Second
+
+// Tamaño a partir del cual se purgan entradas vencidas de la caché
+const revocationCacheSweepSize = 10000
+
+type revokedTokenEntry struct {
+	revoked   bool
+	fetchedAt time.Time
+}
+
+type revokedUserEntry struct {
+	revokedBefore time.Time
+	fetchedAt     time.Time
+}
+
+type revocationCache struct {
+	sync.RWMutex
+	tokens map[string]revokedTokenEntry
+	users  map[string]revokedUserEntry
+}
+
+// RevocationStore guarda la lista de revocación en "revoked_tokens" con una caché en memoria
+type RevocationStore struct {
+	collection *mongo.Collection
+	cache      revocationCache
+}
+
+// NewRevocationStore crea la lista de revocación sobre la colección indicada
+func NewRevocationStore(collection *mongo.Collection) *RevocationStore {
+	return &RevocationStore{
+		collection: collection,
+		cache: revocationCache{
+			tokens: make(map[string]revokedTokenEntry),
+			users:  make(map[string]revokedUserEntry),
+		},
+	}
+}
+
+// RevokeToken agrega el jti de un token a la lista de revocación hasta que el token expire
+func (s *RevocationStore) RevokeToken(ctx context.Context, jti string, email string, expiresAt time.Time) error {
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"jti": jti},
+		bson.M{"$set": bson.M{
+			"jti":        jti,
+			"email":      email,
+			"revoked_at": time.Now(),
+			"expires_at": expiresAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.cache.Lock()
+	s.cache.tokens[jti] = revokedTokenEntry{revoked: true, fetchedAt: time.Now()}
+	s.cache.Unlock()
+	return nil
+}
+
+// RevokeAllTokens invalida todos los tokens del usuario emitidos hasta este momento.
+// El registro expira cuando ya no puede quedar ningún token vivo emitido antes de él.
+// El instante se guarda también en microsegundos (revoked_at_us): revoked_at solo tiene
+// milisegundos y un token emitido justo después de revocar no debe quedar revocado.
+func (s *RevocationStore) RevokeAllTokens(ctx context.Context, email string, tokenTTL time.Duration) error {
+	now := time.Now()
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"email": email, "all": true},
+		bson.M{"$set": bson.M{
+			"email":         email,
+			"all":           true,
+			"revoked_at":    now,
+			"revoked_at_us": now.UnixMicro(),
+			"expires_at":    now.Add(tokenTTL),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	s.cache.Lock()
+	s.cache.users[email] = revokedUserEntry{revokedBefore: now, fetchedAt: now}
+	s.cache.Unlock()
+	return nil
+}
+
+// IsTokenRevoked indica si el token (por jti o por revocación global del usuario) fue revocado.
+// issuedAt debe tener precisión de microsegundos (claim iat_us, ver IssuedAtClaim).
+func (s *RevocationStore) IsTokenRevoked(ctx context.Context, jti string, email string, issuedAt time.Time) (bool, error) {
+	revoked, err := s.isJTIRevoked(ctx, jti)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	revokedBefore, err := s.userRevokedBefore(ctx, email)
+	if err != nil {
+		return false, err
+	}
+	return !revokedBefore.IsZero() && issuedAt.UnixMicro() <= revokedBefore.UnixMicro(), nil
+}
+
+func (s *RevocationStore) isJTIRevoked(ctx context.Context, jti string) (bool, error) {
+	s.cache.RLock()
+	entry, ok := s.cache.tokens[jti]
+	s.cache.RUnlock()
+	if ok && (entry.revoked || time.Since(entry.fetchedAt) < revocationCacheTTL) {
+		return entry.revoked, nil
+	}
+
+	err := s.collection.FindOne(ctx, bson.M{"jti": jti}).Err()
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, err
+	}
+	revoked := err == nil
+
+	s.cache.Lock()
+	s.sweepCache()
+	s.cache.tokens[jti] = revokedTokenEntry{revoked: revoked, fetchedAt: time.Now()}
+	s.cache.Unlock()
+	return revoked, nil
+}
+
+func (s *RevocationStore) userRevokedBefore(ctx context.Context, email string) (time.Time, error) {
+	s.cache.RLock()
+	entry, ok := s.cache.users[email]
+	s.cache.RUnlock()
+	if ok && time.Since(entry.fetchedAt) < revocationCacheTTL {
+		return entry.revokedBefore, nil
+	}
+
+	var doc struct {
+		RevokedAt   time.Time `bson:"revoked_at"`
+		RevokedAtUs int64     `bson:"revoked_at_us"`
+	}
+	err := s.collection.FindOne(ctx, bson.M{"email": email, "all": true}).Decode(&doc)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return time.Time{}, err
+	}
+
+	revokedBefore := doc.RevokedAt
+	if doc.RevokedAtUs != 0 {
+		revokedBefore = time.UnixMicro(doc.RevokedAtUs)
+	}
+
+	s.cache.Lock()
+	s.sweepCache()
+	s.cache.users[email] = revokedUserEntry{revokedBefore: revokedBefore, fetchedAt: time.Now()}
+	s.cache.Unlock()
+	return revokedBefore, nil
+}
+
+// sweepCache elimina entradas vencidas; se llama con el candado tomado
+func (s *RevocationStore) sweepCache() {
+	if len(s.cache.tokens)+len(s.cache.users) < revocationCacheSweepSize {
+		return
+	}
+	for jti, entry := range s.cache.tokens {
+		if time.Since(entry.fetchedAt) >= revocationCacheTTL {
+			delete(s.cache.tokens, jti)
+		}
+	}
+	for email, entry := range s.cache.users {
+		if time.Since(entry.fetchedAt) >= revocationCacheTTL {
+			delete(s.cache.users, email)
+		}
+	}
+}
+
+// IssuedAtClaim es el claim con el instante de emisión en microsegundos. iat solo tiene
+// segundos y no basta para ordenar un token respecto a una revocación global.
+const IssuedAtClaim = "iat_us"
+
+// TokenIssuedAt devuelve el instante de emisión del token: iat_us si existe y, en tokens
+// anteriores a ese claim, iat (se asume el inicio de ese segundo)
+func TokenIssuedAt(claims jwt.MapClaims) (time.Time, error) {
+	if us, ok := claims[IssuedAtClaim].(float64); ok {
+		return time.UnixMicro(int64(us)), nil
+	}
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if issuedAt == nil {
+		return time.Time{}, errors.New("el token no tiene iat")
+	}
+	return issuedAt.Time, nil
+}
@@ -0,0 +1,17 @@
+package utils
+
+// Roles de usuario que viajan en el claim "role" del JWT
+const (
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+	RoleUser    = "user"
+)
+
+// IsValidRole indica si el rol es uno de los roles conocidos
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleAuditor, RoleUser:
+		return true
+	}
+	return false
+}